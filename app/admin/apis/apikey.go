@@ -0,0 +1,74 @@
+package apis
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"wangzhiqiang/skeleton/app/admin/middlewares"
+	"wangzhiqiang/skeleton/app/admin/models"
+	"wangzhiqiang/skeleton/app/admin/service"
+	"wangzhiqiang/skeleton/app/admin/types"
+	"wangzhiqiang/skeleton/pkg/httpx"
+)
+
+type APIKeyApis struct {
+	ctx     context.Context
+	service *service.Service
+}
+
+func NewAPIKey(ctx context.Context) *APIKeyApis {
+	return &APIKeyApis{ctx: ctx, service: new(service.Service)}
+}
+
+// List 当前用户的 API Key 列表
+func (a *APIKeyApis) List(c *gin.Context) {
+	claims, err := middlewares.GetClaims(c)
+	if err != nil {
+		httpx.ApiNoAuth(c, err)
+		return
+	}
+	resp, err := a.service.APIKey.List(a.ctx, claims.UID)
+	if err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	httpx.ApiSuccess[[]*models.SysAPIKey](c, resp)
+}
+
+// Create 创建 API Key，完整密钥只返回一次
+func (a *APIKeyApis) Create(c *gin.Context) {
+	claims, err := middlewares.GetClaims(c)
+	if err != nil {
+		httpx.ApiNoAuth(c, err)
+		return
+	}
+	var req types.APIKeyCreateReq
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	resp, err := a.service.APIKey.Create(a.ctx, claims.UID, &req, c.ClientIP())
+	if err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	httpx.ApiSuccess[*types.APIKeyCreateResp](c, resp)
+}
+
+// Revoke 吊销 API Key
+func (a *APIKeyApis) Revoke(c *gin.Context) {
+	claims, err := middlewares.GetClaims(c)
+	if err != nil {
+		httpx.ApiNoAuth(c, err)
+		return
+	}
+	var req types.IDReq
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	if err := a.service.APIKey.Revoke(a.ctx, claims.UID, &req, c.ClientIP()); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	httpx.ApiSuccess(c, map[string]string{})
+}
@@ -3,17 +3,27 @@ package apis
 import "context"
 
 type Apis struct {
-	Auth *AuthApis
-	Menu *MenuApis
-	Role *RoleApis
-	User *UserApis
+	APIKey   *APIKeyApis
+	Auth     *AuthApis
+	MFA      *MFAApis
+	Menu     *MenuApis
+	OAuth    *OAuthApis
+	Password *PasswordApis
+	Queue    *QueueApis
+	Role     *RoleApis
+	User     *UserApis
 }
 
 func NewApis(ctx context.Context) *Apis {
 	return &Apis{
-		Auth: NewAuth(ctx),
-		Menu: NewMenu(ctx),
-		Role: NewRole(ctx),
-		User: NewUser(ctx),
+		APIKey:   NewAPIKey(ctx),
+		Auth:     NewAuth(ctx),
+		MFA:      NewMFA(ctx),
+		Menu:     NewMenu(ctx),
+		OAuth:    NewOAuth(ctx),
+		Password: NewPassword(ctx),
+		Queue:    NewQueue(ctx),
+		Role:     NewRole(ctx),
+		User:     NewUser(ctx),
 	}
 }
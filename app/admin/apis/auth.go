@@ -3,6 +3,7 @@ package apis
 import (
 	"context"
 	"github.com/gin-gonic/gin"
+	"wangzhiqiang/skeleton/app/admin/middlewares"
 	"wangzhiqiang/skeleton/app/admin/service"
 	"wangzhiqiang/skeleton/app/admin/types"
 	"wangzhiqiang/skeleton/pkg/httpx"
@@ -24,6 +25,7 @@ func (l *AuthApis) Login(c *gin.Context) {
 		return
 	}
 	req.IP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
 	resp, err := l.service.Auth.Login(l.ctx, &req)
 	if err != nil {
 		httpx.ApiError(c, err)
@@ -32,6 +34,38 @@ func (l *AuthApis) Login(c *gin.Context) {
 	httpx.ApiSuccess[*types.LoginResp](c, resp)
 }
 
+// LoginMFA 提交两步验证码完成登录
+func (l *AuthApis) LoginMFA(c *gin.Context) {
+	var req types.MFALoginReq
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	req.IP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+	resp, err := l.service.Auth.LoginMFA(l.ctx, &req)
+	if err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	httpx.ApiSuccess[*types.LoginResp](c, resp)
+}
+
+// LoginMFASetup 登录过程中绑定两步验证，获取 TOTP 密钥
+func (l *AuthApis) LoginMFASetup(c *gin.Context) {
+	var req types.MFATokenReq
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	resp, err := l.service.Auth.LoginMFASetup(l.ctx, &req)
+	if err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	httpx.ApiSuccess[*types.MFASetupResp](c, resp)
+}
+
 func (l *AuthApis) Refresh(c *gin.Context) {
 	var req types.RefreshTokenReq
 	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
@@ -45,3 +79,31 @@ func (l *AuthApis) Refresh(c *gin.Context) {
 	}
 	httpx.ApiSuccess[*types.LoginResp](c, resp)
 }
+
+// Logout 退出登录，注销当前会话
+func (l *AuthApis) Logout(c *gin.Context) {
+	claims, err := middlewares.GetClaims(c)
+	if err != nil {
+		httpx.ApiNoAuth(c, err)
+		return
+	}
+	if err := l.service.Auth.Logout(l.ctx, claims.SessionID); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	httpx.ApiSuccess(c, map[string]string{})
+}
+
+// LogoutAll 退出所有设备的登录
+func (l *AuthApis) LogoutAll(c *gin.Context) {
+	claims, err := middlewares.GetClaims(c)
+	if err != nil {
+		httpx.ApiNoAuth(c, err)
+		return
+	}
+	if err := l.service.Auth.LogoutAll(l.ctx, claims.UID); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	httpx.ApiSuccess(c, map[string]string{})
+}
@@ -0,0 +1,73 @@
+package apis
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"wangzhiqiang/skeleton/app/admin/middlewares"
+	"wangzhiqiang/skeleton/app/admin/service"
+	"wangzhiqiang/skeleton/app/admin/types"
+	"wangzhiqiang/skeleton/pkg/httpx"
+)
+
+type MFAApis struct {
+	ctx     context.Context
+	service *service.Service
+}
+
+func NewMFA(ctx context.Context) *MFAApis {
+	return &MFAApis{ctx: ctx, service: new(service.Service)}
+}
+
+// Setup 获取 TOTP 密钥和 otpauth URI
+func (m *MFAApis) Setup(c *gin.Context) {
+	claims, err := middlewares.GetClaims(c)
+	if err != nil {
+		httpx.ApiNoAuth(c, err)
+		return
+	}
+	resp, err := m.service.MFA.Setup(m.ctx, claims.UID)
+	if err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	httpx.ApiSuccess[*types.MFASetupResp](c, resp)
+}
+
+// Confirm 校验验证码并启用两步验证，返回恢复码
+func (m *MFAApis) Confirm(c *gin.Context) {
+	var req types.MFACodeReq
+	if err := c.ShouldBind(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	claims, err := middlewares.GetClaims(c)
+	if err != nil {
+		httpx.ApiNoAuth(c, err)
+		return
+	}
+	resp, err := m.service.MFA.Confirm(m.ctx, claims.UID, req.Code)
+	if err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	httpx.ApiSuccess[*types.MFAConfirmResp](c, resp)
+}
+
+// Disable 关闭两步验证，需要验证码或恢复码
+func (m *MFAApis) Disable(c *gin.Context) {
+	var req types.MFACodeReq
+	if err := c.ShouldBind(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	claims, err := middlewares.GetClaims(c)
+	if err != nil {
+		httpx.ApiNoAuth(c, err)
+		return
+	}
+	if err := m.service.MFA.Disable(m.ctx, claims.UID, req.Code); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	httpx.ApiSuccess(c, map[string]string{})
+}
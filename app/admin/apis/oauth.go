@@ -0,0 +1,80 @@
+package apis
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"wangzhiqiang/skeleton/app/admin/service"
+	"wangzhiqiang/skeleton/app/admin/types"
+	"wangzhiqiang/skeleton/pkg/app"
+	"wangzhiqiang/skeleton/pkg/httpx"
+)
+
+type OAuthApis struct {
+	ctx     context.Context
+	service *service.Service
+}
+
+func NewOAuth(ctx context.Context) *OAuthApis {
+	return &OAuthApis{ctx: ctx, service: new(service.Service)}
+}
+
+// oauthSessionKey 单点登录参数在会话中的 key，每个身份提供方单独保存
+func oauthSessionKey(provider string) string {
+	return "oauth:" + provider
+}
+
+// Authorize 跳转到身份提供方登录
+func (o *OAuthApis) Authorize(c *gin.Context) {
+	provider := c.Param("provider")
+	url, state, err := o.service.OAuth.Authorize(app.WithApps(c.Request.Context()), provider)
+	if err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	session := sessions.Default(c)
+	session.Set(oauthSessionKey(provider), string(data))
+	if err := session.Save(); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	c.Redirect(http.StatusFound, url)
+}
+
+// Callback 身份提供方回调，校验后返回与密码登录相同的结果
+func (o *OAuthApis) Callback(c *gin.Context) {
+	var req types.OAuthCallbackReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	req.Provider = c.Param("provider")
+	req.IP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+	// 参数只能使用一次
+	session := sessions.Default(c)
+	key := oauthSessionKey(req.Provider)
+	var saved *types.OAuthState
+	if data, ok := session.Get(key).(string); ok {
+		_ = json.Unmarshal([]byte(data), &saved)
+		session.Delete(key)
+		if err := session.Save(); err != nil {
+			httpx.ApiError(c, err)
+			return
+		}
+	}
+	// 换取 token 和校验 ID token 需要访问身份提供方，随请求取消
+	resp, err := o.service.OAuth.Callback(app.WithApps(c.Request.Context()), &req, saved)
+	if err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	httpx.ApiSuccess[*types.LoginResp](c, resp)
+}
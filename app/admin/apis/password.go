@@ -0,0 +1,71 @@
+package apis
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"wangzhiqiang/skeleton/app/admin/middlewares"
+	"wangzhiqiang/skeleton/app/admin/service"
+	"wangzhiqiang/skeleton/app/admin/types"
+	"wangzhiqiang/skeleton/pkg/httpx"
+)
+
+type PasswordApis struct {
+	ctx     context.Context
+	service *service.Service
+}
+
+func NewPassword(ctx context.Context) *PasswordApis {
+	return &PasswordApis{ctx: ctx, service: new(service.Service)}
+}
+
+// Change 修改当前用户密码，返回新会话的 token
+func (p *PasswordApis) Change(c *gin.Context) {
+	claims, err := middlewares.GetClaims(c)
+	if err != nil {
+		httpx.ApiNoAuth(c, err)
+		return
+	}
+	var req types.PasswordChangeReq
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	req.IP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+	resp, err := p.service.Password.Change(p.ctx, claims.UID, &req)
+	if err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	httpx.ApiSuccess[*types.LoginResp](c, resp)
+}
+
+// Forgot 申请找回密码，发送重置链接到邮箱
+func (p *PasswordApis) Forgot(c *gin.Context) {
+	var req types.PasswordForgotReq
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	req.IP = c.ClientIP()
+	if err := p.service.Password.Forgot(p.ctx, &req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	httpx.ApiSuccess(c, map[string]string{})
+}
+
+// Reset 使用重置链接中的 token 设置新密码
+func (p *PasswordApis) Reset(c *gin.Context) {
+	var req types.PasswordResetReq
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	req.IP = c.ClientIP()
+	if err := p.service.Password.Reset(p.ctx, &req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	httpx.ApiSuccess(c, map[string]string{})
+}
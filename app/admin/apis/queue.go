@@ -0,0 +1,138 @@
+package apis
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"wangzhiqiang/skeleton/app/admin/service"
+	"wangzhiqiang/skeleton/app/admin/types"
+	"wangzhiqiang/skeleton/pkg/httpx"
+)
+
+type QueueApis struct {
+	ctx     context.Context
+	service *service.Service
+}
+
+func NewQueue(ctx context.Context) *QueueApis {
+	return &QueueApis{ctx: ctx, service: new(service.Service)}
+}
+
+// queueError 当前驱动不支持任务管理时返回 501
+func queueError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrQueueUnsupported) {
+		httpx.ApiErrWithCode(c, err, http.StatusNotImplemented)
+		return
+	}
+	httpx.ApiError(c, err)
+}
+
+func (q *QueueApis) List(c *gin.Context) {
+	var req types.QueueListReq
+	if err := c.ShouldBind(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	resp, err := q.service.Queue.List(q.ctx, &req)
+	if err != nil {
+		queueError(c, err)
+		return
+	}
+	httpx.ApiSuccess(c, resp)
+}
+
+func (q *QueueApis) View(c *gin.Context) {
+	var req types.IDReq
+	if err := c.ShouldBind(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	info, err := q.service.Queue.View(q.ctx, req.ID)
+	if err != nil {
+		queueError(c, err)
+		return
+	}
+	httpx.ApiSuccess(c, info)
+}
+
+func (q *QueueApis) Delete(c *gin.Context) {
+	var req types.IDReq
+	if err := c.ShouldBind(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	if err := q.service.Queue.Delete(q.ctx, req.ID); err != nil {
+		queueError(c, err)
+		return
+	}
+	httpx.ApiSuccess(c, map[string]string{})
+}
+
+func (q *QueueApis) Purge(c *gin.Context) {
+	var req types.QueuePurgeReq
+	if err := c.ShouldBind(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	count, err := q.service.Queue.Purge(q.ctx, &req)
+	if err != nil {
+		queueError(c, err)
+		return
+	}
+	httpx.ApiSuccess(c, map[string]int64{"count": count})
+}
+
+func (q *QueueApis) FailedList(c *gin.Context) {
+	var req types.QueueFailedListReq
+	if err := c.ShouldBind(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	resp, err := q.service.Queue.FailedList(q.ctx, &req)
+	if err != nil {
+		queueError(c, err)
+		return
+	}
+	httpx.ApiSuccess(c, resp)
+}
+
+func (q *QueueApis) FailedView(c *gin.Context) {
+	var req types.IDReq
+	if err := c.ShouldBind(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	info, err := q.service.Queue.FailedView(q.ctx, req.ID)
+	if err != nil {
+		queueError(c, err)
+		return
+	}
+	httpx.ApiSuccess(c, info)
+}
+
+func (q *QueueApis) FailedRetry(c *gin.Context) {
+	var req types.IDReq
+	if err := c.ShouldBind(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	if err := q.service.Queue.FailedRetry(q.ctx, req.ID); err != nil {
+		queueError(c, err)
+		return
+	}
+	httpx.ApiSuccess(c, map[string]string{})
+}
+
+func (q *QueueApis) FailedDelete(c *gin.Context) {
+	var req types.IDReq
+	if err := c.ShouldBind(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	if err := q.service.Queue.FailedDelete(q.ctx, req.ID); err != nil {
+		queueError(c, err)
+		return
+	}
+	httpx.ApiSuccess(c, map[string]string{})
+}
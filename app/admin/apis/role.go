@@ -73,6 +73,25 @@ func (r *RoleApis) Edit(c *gin.Context) {
 	httpx.ApiSuccess(c, map[string]string{})
 }
 
+// MFA 设置角色是否要求两步验证
+func (r *RoleApis) MFA(c *gin.Context) {
+	var req types.RoleMFAReq
+	if err := c.ShouldBind(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	claims, err := middlewares.GetClaims(c)
+	if err != nil {
+		httpx.ApiNoAuth(c, err)
+		return
+	}
+	if err := r.service.Role.MFA(r.ctx, &req, claims.UID); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	httpx.ApiSuccess(c, map[string]string{})
+}
+
 func (r *RoleApis) Delete(c *gin.Context) {
 	var req types.RoleReq
 	if err := c.ShouldBind(&req); err != nil {
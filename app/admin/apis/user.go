@@ -3,6 +3,7 @@ package apis
 import (
 	"context"
 	"github.com/gin-gonic/gin"
+	"wangzhiqiang/skeleton/app/admin/middlewares"
 	"wangzhiqiang/skeleton/app/admin/service"
 	"wangzhiqiang/skeleton/app/admin/types"
 	"wangzhiqiang/skeleton/pkg/httpx"
@@ -84,3 +85,36 @@ func (u *UserApis) Delete(c *gin.Context) {
 	}
 	httpx.ApiSuccess(c, map[string]string{})
 }
+
+// Unlock 解锁因登录失败次数过多被锁定的账号
+func (u *UserApis) Unlock(c *gin.Context) {
+	var req types.IDReq
+	if err := c.ShouldBind(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	claims, err := middlewares.GetClaims(c)
+	if err != nil {
+		httpx.ApiNoAuth(c, err)
+		return
+	}
+	if err := u.service.User.Unlock(u.ctx, &req, claims.UID, c.ClientIP()); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	httpx.ApiSuccess(c, map[string]string{})
+}
+
+// Kick 踢下线，注销用户的所有会话
+func (u *UserApis) Kick(c *gin.Context) {
+	var req types.IDReq
+	if err := c.ShouldBind(&req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	if err := u.service.User.Kick(u.ctx, &req); err != nil {
+		httpx.ApiError(c, err)
+		return
+	}
+	httpx.ApiSuccess(c, map[string]string{})
+}
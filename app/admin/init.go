@@ -4,9 +4,12 @@ import (
 	"context"
 	"wangzhiqiang/skeleton/app/admin/mock"
 	"wangzhiqiang/skeleton/app/admin/models"
+	"wangzhiqiang/skeleton/app/admin/service"
 	appModels "wangzhiqiang/skeleton/app/models"
 	"wangzhiqiang/skeleton/pkg/app"
+	"wangzhiqiang/skeleton/pkg/database"
 	"wangzhiqiang/skeleton/pkg/httpx"
+	"wangzhiqiang/skeleton/pkg/queue"
 )
 
 type adminAppInit struct {
@@ -21,13 +24,26 @@ func (a *adminAppInit) Init(ctx context.Context) {
 		models.SysUser{},
 		models.SysMenu{},
 		models.SysRole{},
+		models.SysLoginAttempt{},
+		models.SysMFARecoveryCode{},
+		models.SysAPIKey{},
+		models.SysPasswordReset{},
+		models.SysUserIdentity{},
 		appModels.SysAccessLog{},
+		appModels.SysAuditLog{},
 	)
-	_ = mock.Save(apps.Enforcer, apps.DB)
+	// 只有 database 驱动支持队列任务管理
+	_, queueManage := apps.Queue.(*queue.Gorm)
+	_ = mock.Save(apps.Enforcer, apps.DB, queueManage)
 }
 
 func init() {
 	app.RegisterAppInit(&adminAppInit{})
+	// 包含加密字段的模型，encrypt:rotate 命令轮换密钥时重新加密
+	database.RegisterEncryptedModel(&models.SysUser{}, &appModels.SysAccessLog{})
+
+	// 重置密码邮件在发送时生成 token，任务定义在 service 中
+	queue.Register(&service.PasswordResetMailTask{})
 
 	httpx.RegisterRoute(&Route{})
 }
@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"wangzhiqiang/skeleton/pkg/httpx"
+	"wangzhiqiang/skeleton/pkg/jwts"
+)
+
+// APIKeyAuthenticator 校验 API Key，返回所属用户信息和授权范围
+type APIKeyAuthenticator func(key string) (*jwts.Claims, []string, error)
+
+// APIKeyAuth 返回 API Key 认证中间件，需放在 JWTAuth 之前
+//
+//	请求头为 Authorization: ApiKey xxx 时校验 API Key 和授权范围，其他请求交给 JWTAuth
+//	授权范围格式为 资源:操作，资源为 prefix 之后的第一段路径，GET 请求为 read，其他为 write，* 表示全部，例如 user:read、queue:*、*
+func APIKeyAuth(authenticate APIKeyAuthenticator, prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, key, found := strings.Cut(strings.TrimSpace(c.GetHeader("Authorization")), " ")
+		if !found || !strings.EqualFold(scheme, "ApiKey") {
+			c.Next()
+			return
+		}
+		claims, scopes, err := authenticate(strings.TrimSpace(key))
+		if err != nil {
+			httpx.ApiNoAuth(c, err)
+			c.Abort()
+			return
+		}
+		scope := requestScope(c, prefix)
+		if !scopeAllowed(scopes, scope) {
+			httpx.ApiNoForbidden(c, fmt.Errorf("API Key 未授权 %s", scope))
+			c.Abort()
+			return
+		}
+		c.Set(CtxKeyUserClaims, claims)
+		c.Next()
+	}
+}
+
+// requestScope 返回请求需要的授权范围
+func requestScope(c *gin.Context, prefix string) string {
+	path := strings.TrimPrefix(c.FullPath(), strings.TrimSuffix(prefix, "/")+"/")
+	resource, _, _ := strings.Cut(path, "/")
+	action := "write"
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		action = "read"
+	}
+	return resource + ":" + action
+}
+
+// scopeAllowed 授权范围是否包含 scope
+func scopeAllowed(scopes []string, scope string) bool {
+	resource, action, _ := strings.Cut(scope, ":")
+	for _, s := range scopes {
+		if s == "*" {
+			return true
+		}
+		r, a, _ := strings.Cut(s, ":")
+		if (r == "*" || r == resource) && (a == "*" || a == action) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wangzhiqiang/skeleton/pkg/httpx"
+	"wangzhiqiang/skeleton/pkg/jwts"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAPIKeyRouter 按管理后台路由的结构注册：会话分组只接受 JWT，其他分组支持 API Key
+func newAPIKeyRouter(t *testing.T, scopes []string) *gin.Engine {
+	j, err := jwts.NewJWT(&jwts.Config{Secret: "test-secret", Expiration: 3600})
+	require.NoError(t, err)
+	authenticate := func(key string) (*jwts.Claims, []string, error) {
+		if key != "sk_test" {
+			return nil, nil, assert.AnError
+		}
+		return &jwts.Claims{UID: 1, TokenType: jwts.APIKeyTokenType}, scopes, nil
+	}
+	ok := func(c *gin.Context) { httpx.ApiSuccess(c, requestScope(c, "/api/admin")) }
+
+	r := gin.New()
+	admin := r.Group("/api/admin")
+	session := admin.Group("", JWTAuth(j))
+	session.POST("/password/change", ok)
+	session.POST("/mfa/setup", ok)
+	session.POST("/apikey/create", ok)
+	admin.Use(APIKeyAuth(authenticate, "/api/admin"), JWTAuth(j))
+	admin.GET("/user", ok)
+	admin.GET("/user/view", ok)
+	admin.POST("/user/create", ok)
+	admin.DELETE("/queue/failed/delete", ok)
+	return r
+}
+
+// doAPIKey 使用 API Key 请求，返回响应中的业务码和数据
+func doAPIKey(r *gin.Engine, method, path, key string) (int, string) {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "ApiKey "+key)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var resp httpx.RespResult[string]
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp.Code, resp.Data
+}
+
+func TestAPIKeyAuth_Scope(t *testing.T) {
+	r := newAPIKeyRouter(t, []string{"user:read", "queue:*"})
+	tests := []struct {
+		method, path string
+		code         int
+		scope        string
+	}{
+		{http.MethodGet, "/api/admin/user", http.StatusOK, "user:read"},
+		{http.MethodGet, "/api/admin/user/view?id=1", http.StatusOK, "user:read"},
+		{http.MethodPost, "/api/admin/user/create", http.StatusForbidden, ""},
+		{http.MethodDelete, "/api/admin/queue/failed/delete", http.StatusOK, "queue:write"},
+	}
+	for _, tt := range tests {
+		code, scope := doAPIKey(r, tt.method, tt.path, "sk_test")
+		assert.Equal(t, tt.code, code, tt.path)
+		assert.Equal(t, tt.scope, scope, tt.path)
+	}
+
+	// 无效的 API Key
+	code, _ := doAPIKey(r, http.MethodGet, "/api/admin/user", "sk_invalid")
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestAPIKeyAuth_SessionRoutes(t *testing.T) {
+	r := newAPIKeyRouter(t, []string{"*"})
+	// 修改密码、两步验证和 API Key 管理只接受登录会话
+	for _, path := range []string{"/api/admin/password/change", "/api/admin/mfa/setup", "/api/admin/apikey/create"} {
+		code, _ := doAPIKey(r, http.MethodPost, path, "sk_test")
+		assert.Equal(t, http.StatusUnauthorized, code, path)
+	}
+}
+
+func TestScopeAllowed(t *testing.T) {
+	tests := []struct {
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{[]string{"*"}, "user:write", true},
+		{[]string{"user:*"}, "user:write", true},
+		{[]string{"*:read"}, "role:read", true},
+		{[]string{"user:read"}, "user:write", false},
+		{[]string{"role:write"}, "user:write", false},
+		{nil, "user:read", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, scopeAllowed(tt.scopes, tt.scope), "%v %s", tt.scopes, tt.scope)
+	}
+}
@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"strings"
+	"wangzhiqiang/skeleton/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenExtractor 从请求中提取 token，没有时返回空字符串
+type TokenExtractor func(c *gin.Context) string
+
+// Extractors 根据配置生成 token 来源，按请求头、cookie、查询参数的顺序尝试
+func Extractors(src *config.TokenSourceConfig) []TokenExtractor {
+	header := src.Header
+	if header == "" {
+		header = "Authorization"
+	}
+	extractors := []TokenExtractor{FromHeader(header)}
+	if src.Cookie != "" {
+		extractors = append(extractors, FromCookie(src.Cookie))
+	}
+	if src.Query != "" {
+		extractors = append(extractors, FromQuery(src.Query))
+	}
+	return extractors
+}
+
+// FromHeader 从请求头提取 Bearer token，其他认证方案（如 ApiKey）和没有方案的裸 token 都不接受
+func FromHeader(name string) TokenExtractor {
+	return func(c *gin.Context) string {
+		scheme, token, found := strings.Cut(strings.TrimSpace(c.GetHeader(name)), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			return ""
+		}
+		return strings.TrimSpace(token)
+	}
+}
+
+// FromCookie 从 cookie 提取 token
+func FromCookie(name string) TokenExtractor {
+	return func(c *gin.Context) string {
+		token, _ := c.Cookie(name)
+		return token
+	}
+}
+
+// FromQuery 从查询参数提取 token，用于无法设置请求头的 WebSocket、SSE 握手
+func FromQuery(name string) TokenExtractor {
+	return func(c *gin.Context) string {
+		return c.Query(name)
+	}
+}
+
+// extractToken 依次尝试各个来源，返回第一个非空的 token
+func extractToken(c *gin.Context, extractors []TokenExtractor) string {
+	for _, extract := range extractors {
+		if token := extract(c); token != "" {
+			return token
+		}
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wangzhiqiang/skeleton/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// newExtractContext 构造带有请求头、cookie 和查询参数的请求
+func newExtractContext(header, cookie, query string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/?token="+query, nil)
+	if header != "" {
+		c.Request.Header.Set("Authorization", header)
+	}
+	if cookie != "" {
+		c.Request.AddCookie(&http.Cookie{Name: "token", Value: cookie})
+	}
+	return c
+}
+
+func TestFromHeader(t *testing.T) {
+	extract := FromHeader("Authorization")
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"Bearer abc", "abc"},
+		{"bearer  abc ", "abc"},
+		{"abc", ""},
+		{"ApiKey abc", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, extract(newExtractContext(tt.header, "", "")), tt.header)
+	}
+}
+
+func TestExtractors_Precedence(t *testing.T) {
+	all := Extractors(&config.TokenSourceConfig{Cookie: "token", Query: "token"})
+	tests := []struct {
+		name                  string
+		header, cookie, query string
+		want                  string
+	}{
+		{"header first", "Bearer h", "c", "q", "h"},
+		{"cookie before query", "", "c", "q", "c"},
+		{"query last", "", "", "q", "q"},
+		{"bare header falls through", "h", "", "q", "q"},
+		{"none", "", "", "", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, extractToken(newExtractContext(tt.header, tt.cookie, tt.query), all), tt.name)
+	}
+
+	// 未配置的来源不读取
+	headerOnly := Extractors(&config.TokenSourceConfig{})
+	assert.Empty(t, extractToken(newExtractContext("", "c", "q"), headerOnly))
+	assert.Equal(t, "h", extractToken(newExtractContext("Bearer h", "", ""), headerOnly))
+}
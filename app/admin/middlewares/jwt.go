@@ -24,23 +24,37 @@ func GetClaims(c *gin.Context) (*jwts.Claims, error) {
 	return claims, nil
 }
 
-// JWTAuth 返回 JWT 认证中间件
-func JWTAuth(jwt *jwts.JWT) gin.HandlerFunc {
+// JWTAuth 返回 JWT 认证中间件，extractors 为 token 来源，按顺序尝试，默认从 Authorization 请求头提取
+func JWTAuth(jwt *jwts.JWT, extractors ...TokenExtractor) gin.HandlerFunc {
+	if len(extractors) == 0 {
+		extractors = []TokenExtractor{FromHeader("Authorization")}
+	}
 	return func(c *gin.Context) {
-		token := c.Request.Header.Get("Authorization")
+		// 已通过 APIKeyAuth 认证
+		if _, exists := c.Get(CtxKeyUserClaims); exists {
+			c.Next()
+			return
+		}
+		token := extractToken(c, extractors)
 		if token == "" {
 			httpx.ApiNoAuth(c, errors.New("缺少 token"))
 			c.Abort()
 			return
 		}
 
-		claims, err := jwt.Parse(token)
+		// 只接受 access token，refresh token 只能用于续签
+		claims, err := jwt.Parse(token, jwts.RequireTokenType(jwts.AccessTokenType))
 		if err != nil {
 			if errors.Is(err, jwts.TokenExpired) {
 				httpx.ApiNoAuth(c, errors.New("登录已过期，请重新登录"))
 				c.Abort()
 				return
 			}
+			if errors.Is(err, jwts.ErrSessionRevoked) {
+				httpx.ApiNoAuth(c, err)
+				c.Abort()
+				return
+			}
 			httpx.ApiNoAuth(c, fmt.Errorf("token 无效: %w", err))
 			c.Abort()
 			return
@@ -10,7 +10,8 @@ import (
 	"wangzhiqiang/skeleton/pkg/cryptox"
 )
 
-func Save(e *casbin.Enforcer, db *gorm.DB) error {
+// Save 写入演示数据，queueManage 为 false 时当前队列驱动不支持任务管理，不创建队列管理菜单
+func Save(e *casbin.Enforcer, db *gorm.DB, queueManage bool) error {
 	prefix := "/api/admin"
 
 	// ---------------- 创建主菜单 ----------------
@@ -18,8 +19,12 @@ func Save(e *casbin.Enforcer, db *gorm.DB) error {
 	usersMenu := models.SysMenu{Name: "用户管理", Path: prefix + "/user", Method: datatypes.JSONSlice[string]{http.MethodGet}, Sort: 2, Type: models.MenuTypeMenu}
 	rolesMenu := models.SysMenu{Name: "角色管理", Path: prefix + "/role", Method: datatypes.JSONSlice[string]{http.MethodGet}, Sort: 3, Type: models.MenuTypeMenu}
 	menusMenu := models.SysMenu{Name: "菜单管理", Path: prefix + "/menu", Method: datatypes.JSONSlice[string]{http.MethodGet}, Sort: 4, Type: models.MenuTypeMenu}
+	queueMenu := models.SysMenu{Name: "队列管理", Path: prefix + "/queue", Method: datatypes.JSONSlice[string]{http.MethodGet}, Sort: 5, Type: models.MenuTypeMenu}
 
 	menus := []*models.SysMenu{&dashboard, &usersMenu, &rolesMenu, &menusMenu}
+	if queueManage {
+		menus = append(menus, &queueMenu)
+	}
 	for _, m := range menus {
 		if err := db.Create(m).Error; err != nil {
 			return err
@@ -31,6 +36,8 @@ func Save(e *casbin.Enforcer, db *gorm.DB) error {
 	userView := models.SysMenu{Name: "查看用户", Path: prefix + "/user/view", Method: datatypes.JSONSlice[string]{http.MethodGet}, ParentID: usersMenu.ID, Type: models.MenuTypeButton}
 	userEdit := models.SysMenu{Name: "编辑用户", Path: prefix + "/user/edit", Method: datatypes.JSONSlice[string]{http.MethodPut}, ParentID: usersMenu.ID, Type: models.MenuTypeButton}
 	userDelete := models.SysMenu{Name: "删除用户", Path: prefix + "/user/delete", Method: datatypes.JSONSlice[string]{http.MethodDelete}, ParentID: usersMenu.ID, Type: models.MenuTypeButton}
+	userKick := models.SysMenu{Name: "踢下线", Path: prefix + "/user/kick", Method: datatypes.JSONSlice[string]{http.MethodPost}, ParentID: usersMenu.ID, Type: models.MenuTypeButton}
+	userUnlock := models.SysMenu{Name: "解锁账号", Path: prefix + "/user/unlock", Method: datatypes.JSONSlice[string]{http.MethodPost}, ParentID: usersMenu.ID, Type: models.MenuTypeButton}
 
 	// ---------------- 角色管理操作 ----------------
 	roleCreate := models.SysMenu{Name: "创建角色", Path: prefix + "/role/create", Method: datatypes.JSONSlice[string]{http.MethodPost}, ParentID: rolesMenu.ID, Type: models.MenuTypeButton}
@@ -38,6 +45,7 @@ func Save(e *casbin.Enforcer, db *gorm.DB) error {
 	roleEdit := models.SysMenu{Name: "编辑角色", Path: prefix + "/role/edit", Method: datatypes.JSONSlice[string]{http.MethodPut}, ParentID: rolesMenu.ID, Type: models.MenuTypeButton}
 	roleDelete := models.SysMenu{Name: "删除角色", Path: prefix + "/role/delete", Method: datatypes.JSONSlice[string]{http.MethodDelete}, ParentID: rolesMenu.ID, Type: models.MenuTypeButton}
 	roleAuth := models.SysMenu{Name: "授权权限", Path: prefix + "/role/auth", Method: datatypes.JSONSlice[string]{http.MethodPost}, ParentID: rolesMenu.ID, Type: models.MenuTypeButton}
+	roleMFA := models.SysMenu{Name: "两步验证要求", Path: prefix + "/role/mfa", Method: datatypes.JSONSlice[string]{http.MethodPost}, ParentID: rolesMenu.ID, Type: models.MenuTypeButton}
 	roleAuthList := models.SysMenu{Name: "权限列表", Path: prefix + "/role/auth/list", Method: datatypes.JSONSlice[string]{http.MethodGet}, ParentID: rolesMenu.ID, Type: models.MenuTypeButton}
 
 	// ---------------- 菜单管理操作 ----------------
@@ -46,11 +54,23 @@ func Save(e *casbin.Enforcer, db *gorm.DB) error {
 	menuEdit := models.SysMenu{Name: "编辑菜单", Path: prefix + "/menu/edit", Method: datatypes.JSONSlice[string]{http.MethodPut}, ParentID: menusMenu.ID, Type: models.MenuTypeButton}
 	menuDelete := models.SysMenu{Name: "删除菜单", Path: prefix + "/menu/delete", Method: datatypes.JSONSlice[string]{http.MethodDelete}, ParentID: menusMenu.ID, Type: models.MenuTypeButton}
 
+	// ---------------- 队列管理操作 ----------------
+	queueView := models.SysMenu{Name: "查看任务", Path: prefix + "/queue/view", Method: datatypes.JSONSlice[string]{http.MethodGet}, ParentID: queueMenu.ID, Type: models.MenuTypeButton}
+	queueDelete := models.SysMenu{Name: "删除任务", Path: prefix + "/queue/delete", Method: datatypes.JSONSlice[string]{http.MethodDelete}, ParentID: queueMenu.ID, Type: models.MenuTypeButton}
+	queuePurge := models.SysMenu{Name: "清空队列", Path: prefix + "/queue/purge", Method: datatypes.JSONSlice[string]{http.MethodDelete}, ParentID: queueMenu.ID, Type: models.MenuTypeButton}
+	queueFailed := models.SysMenu{Name: "死信列表", Path: prefix + "/queue/failed", Method: datatypes.JSONSlice[string]{http.MethodGet}, ParentID: queueMenu.ID, Type: models.MenuTypeButton}
+	queueFailedView := models.SysMenu{Name: "查看死信", Path: prefix + "/queue/failed/view", Method: datatypes.JSONSlice[string]{http.MethodGet}, ParentID: queueMenu.ID, Type: models.MenuTypeButton}
+	queueFailedRetry := models.SysMenu{Name: "重试死信", Path: prefix + "/queue/failed/retry", Method: datatypes.JSONSlice[string]{http.MethodPost}, ParentID: queueMenu.ID, Type: models.MenuTypeButton}
+	queueFailedDelete := models.SysMenu{Name: "删除死信", Path: prefix + "/queue/failed/delete", Method: datatypes.JSONSlice[string]{http.MethodDelete}, ParentID: queueMenu.ID, Type: models.MenuTypeButton}
+
 	buttons := []*models.SysMenu{
-		&userCreate, &userView, &userEdit, &userDelete,
-		&roleCreate, &roleView, &roleEdit, &roleDelete, &roleAuth, &roleAuthList,
+		&userCreate, &userView, &userEdit, &userDelete, &userKick, &userUnlock,
+		&roleCreate, &roleView, &roleEdit, &roleDelete, &roleAuth, &roleAuthList, &roleMFA,
 		&menuCreate, &menuView, &menuEdit, &menuDelete,
 	}
+	if queueManage {
+		buttons = append(buttons, &queueView, &queueDelete, &queuePurge, &queueFailed, &queueFailedView, &queueFailedRetry, &queueFailedDelete)
+	}
 	for _, b := range buttons {
 		if err := db.Create(b).Error; err != nil {
 			return err
@@ -77,8 +97,12 @@ func Save(e *casbin.Enforcer, db *gorm.DB) error {
 	}
 
 	// ---------------- 创建用户 ----------------
-	adminUser := models.SysUser{Name: "admin", Email: "admin@example.com", Password: cryptox.HashMake("123456"), Roles: []*models.SysRole{&adminRole}}
-	editorUser := models.SysUser{Name: "editor", Email: "editor@example.com", Password: cryptox.HashMake("123456"), Roles: []*models.SysRole{&editorRole}}
+	password, err := cryptox.HashMake("123456")
+	if err != nil {
+		return err
+	}
+	adminUser := models.SysUser{Name: "admin", Email: "admin@example.com", Password: password, Roles: []*models.SysRole{&adminRole}}
+	editorUser := models.SysUser{Name: "editor", Email: "editor@example.com", Password: password, Roles: []*models.SysRole{&editorRole}}
 
 	users := []*models.SysUser{&adminUser, &editorUser}
 	for _, u := range users {
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+	"wangzhiqiang/skeleton/pkg/database"
+)
+
+// SysAPIKey 机器调用使用的 API Key，归属于用户，权限不超过该用户的角色权限
+//
+//	只保存密钥的 SHA256，前缀明文保存用于识别和查找
+type SysAPIKey struct {
+	database.BaseModel
+	UserID     uint       `gorm:"index;comment:所属用户ID" json:"user_id"`
+	Name       string     `gorm:"type:varchar(50);default:'';comment:名称" json:"name"`
+	Prefix     string     `gorm:"type:varchar(20);uniqueIndex;comment:密钥前缀" json:"prefix"`
+	KeyHash    string     `gorm:"type:varchar(64);comment:密钥SHA256" json:"-"`
+	Scopes     []string   `gorm:"type:varchar(500);serializer:json;comment:授权范围" json:"scopes"`
+	ExpiresAt  *time.Time `gorm:"comment:过期时间，为空表示不过期" json:"expires_at"`
+	LastUsedAt *time.Time `gorm:"comment:最近使用时间" json:"last_used_at"`
+	RevokedAt  *time.Time `gorm:"comment:吊销时间" json:"revoked_at"`
+}
+
+// Active 是否可用
+func (k *SysAPIKey) Active() bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(time.Now()))
+}
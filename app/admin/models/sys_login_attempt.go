@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// SysLoginAttempt 登录失败计数，Subject 为 account:邮箱 或 ip:地址
+type SysLoginAttempt struct {
+	Subject       string     `gorm:"primaryKey;type:varchar(150);comment:计数对象" json:"subject"`
+	Failures      int        `gorm:"type:int;default:0;comment:窗口内失败次数" json:"failures"`
+	FirstFailedAt time.Time  `gorm:"comment:窗口内首次失败时间" json:"first_failed_at"`
+	LastFailedAt  time.Time  `gorm:"comment:最近一次失败时间" json:"last_failed_at"`
+	LockedUntil   *time.Time `gorm:"comment:锁定截止时间" json:"locked_until"`
+}
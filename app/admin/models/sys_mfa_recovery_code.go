@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// SysMFARecoveryCode 两步验证恢复码，只保存哈希，每个恢复码只能使用一次
+type SysMFARecoveryCode struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement;comment:主键ID" json:"id"`
+	UserID    uint       `gorm:"index;comment:用户ID" json:"user_id"`
+	CodeHash  string     `gorm:"type:varchar(64);index;comment:恢复码SHA256" json:"-"`
+	UsedAt    *time.Time `gorm:"comment:使用时间" json:"used_at"`
+	CreatedAt time.Time  `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+}
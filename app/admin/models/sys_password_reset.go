@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// SysPasswordReset 找回密码 token，只保存哈希，使用后或重新申请后失效
+type SysPasswordReset struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement;comment:主键ID" json:"id"`
+	UserID    uint       `gorm:"index;comment:用户ID" json:"user_id"`
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex;comment:token SHA256" json:"-"`
+	Ip        string     `gorm:"type:varchar(45);comment:申请IP" json:"ip"`
+	ExpiresAt time.Time  `gorm:"comment:过期时间" json:"expires_at"`
+	UsedAt    *time.Time `gorm:"comment:使用时间，重新申请时旧 token 同样标记为已使用" json:"used_at"`
+	CreatedAt time.Time  `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+}
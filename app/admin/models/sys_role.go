@@ -7,11 +7,12 @@ import (
 
 type SysRole struct {
 	database.BaseModel
-	Name   string     `gorm:"type:varchar(50);uniqueIndex;comment:角色名称" json:"name"`
-	Code   string     `gorm:"type:varchar(50);uniqueIndex;comment:角色编码" json:"code"`
-	Remark string     `gorm:"type:varchar(255);comment:备注" json:"remark"`
-	Users  []*SysUser `gorm:"many2many:sys_user_roles;" json:"users"`
-	Menus  []*SysMenu `gorm:"many2many:sys_role_menus;" json:"menus"`
+	Name       string     `gorm:"type:varchar(50);uniqueIndex;comment:角色名称" json:"name"`
+	Code       string     `gorm:"type:varchar(50);uniqueIndex;comment:角色编码" json:"code"`
+	Remark     string     `gorm:"type:varchar(255);comment:备注" json:"remark"`
+	RequireMFA bool       `gorm:"default:false;comment:是否要求两步验证" json:"require_mfa"`
+	Users      []*SysUser `gorm:"many2many:sys_user_roles;" json:"users"`
+	Menus      []*SysMenu `gorm:"many2many:sys_role_menus;" json:"menus"`
 }
 
 // GetID 返回角色ID（string 类型）
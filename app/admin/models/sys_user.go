@@ -10,11 +10,15 @@ type SysUser struct {
 	database.BaseModel
 	Email     string    `gorm:"type:varchar(100);uniqueIndex;default:'';index:idx_email;comment:电子邮箱" json:"email"`
 	Name      string    `gorm:"type:varchar(50);default:'';comment:昵称" json:"name"`
-	Phone     string    `gorm:"type:bigint unsigned;default:0;comment:手机号" json:"phone"`
+	Phone     string    `gorm:"type:varchar(255);default:'';serializer:encrypted;comment:手机号(加密)" json:"phone"`
 	Password  string    `gorm:"type:varchar(255);default:'';comment:密码" json:"-"`
 	LastLogin time.Time `gorm:"default:null;comment:最后登录时间" json:"last_login,omitempty"`
 	LastIp    string    `gorm:"type:varchar(100);default:'';comment:最后登录IP" json:"last_ip,omitempty"`
 
+	MFAEnabled  bool   `gorm:"default:false;comment:是否启用两步验证" json:"mfa_enabled"`
+	MFASecret   string `gorm:"type:varchar(255);default:'';serializer:encrypted;comment:TOTP密钥(加密)" json:"-"`
+	MFALastStep int64  `gorm:"default:0;comment:最近使用的TOTP周期，防止验证码重放" json:"-"`
+
 	Roles []*SysRole `gorm:"many2many:sys_user_roles;" json:"roles"`
 }
 
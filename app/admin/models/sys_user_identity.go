@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// SysUserIdentity 单点登录身份绑定，首次关联后按签发者和 subject 匹配用户，不再依赖邮箱
+type SysUserIdentity struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement;comment:主键ID" json:"id"`
+	UserID    uint      `gorm:"index;comment:用户ID" json:"user_id"`
+	Provider  string    `gorm:"type:varchar(50);comment:登录方式" json:"provider"`
+	Issuer    string    `gorm:"type:varchar(255);uniqueIndex:idx_issuer_subject;comment:身份提供方" json:"issuer"`
+	Subject   string    `gorm:"type:varchar(255);uniqueIndex:idx_issuer_subject;comment:身份提供方用户标识" json:"subject"`
+	Email     string    `gorm:"type:varchar(100);comment:关联时的邮箱" json:"email"`
+	CreatedAt time.Time `gorm:"autoCreateTime;comment:创建时间" json:"created_at"`
+}
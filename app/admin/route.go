@@ -7,10 +7,12 @@ import (
 	"net/http"
 	"wangzhiqiang/skeleton/app/admin/apis"
 	"wangzhiqiang/skeleton/app/admin/middlewares"
+	"wangzhiqiang/skeleton/app/admin/service"
 	appMiddlewares "wangzhiqiang/skeleton/app/middlewares"
 	"wangzhiqiang/skeleton/pkg/app"
 	"wangzhiqiang/skeleton/pkg/httpx"
 	"wangzhiqiang/skeleton/pkg/httpx/mws"
+	"wangzhiqiang/skeleton/pkg/jwts"
 )
 
 type Route struct {
@@ -24,30 +26,56 @@ func (a *Route) Routes(ctx context.Context, g *gin.Engine) error {
 	if err != nil {
 		return err
 	}
-	jwtAuth := middlewares.JWTAuth(apps.JWT)
+	// token 来源在 system.token.admin 中配置，WebSocket、SSE 等分组可以使用单独的配置
+	tokenSource := apps.Config.System.TokenSource("admin")
+	jwtAuth := middlewares.JWTAuth(apps.JWT, middlewares.Extractors(tokenSource)...)
+	apiKeyAuth := middlewares.APIKeyAuth(func(key string) (*jwts.Claims, []string, error) {
+		return new(service.APIKeyService).Authenticate(ctx, key)
+	}, "/api/admin")
 	permission := middlewares.CheckPermission(apps.Enforcer, apps.Config)
-	accessLog := appMiddlewares.AccessLog(apps.DB)
+	// 访问日志不记录 token 查询参数的值
+	accessLog := appMiddlewares.AccessLog(apps.DB, tokenSource.Query, tokenSource.Cookie)
 	g.Use(mws.Core())
 	api := apis.NewApis(ctx)
 	adminGroup := g.Group("/api/admin")
 	{
-		// 登录不需要认证
+		// 登录和刷新token不需要认证，刷新时由 refresh token 自身校验
 		adminGroup.POST("/login", api.Auth.Login)
-		// 用户和角色操作需要认证和权限中间件
-		adminGroup.Use(jwtAuth)
-		adminGroup.Use(accessLog)
-		adminGroup.Use(permission)
-
-		// 刷新token
 		adminGroup.POST("/refresh", api.Auth.Refresh)
+		adminGroup.POST("/login/mfa", api.Auth.LoginMFA)                // 提交两步验证码完成登录
+		adminGroup.POST("/login/mfa/setup", api.Auth.LoginMFASetup)     // 角色要求两步验证时登录过程中绑定
+		adminGroup.GET("/oauth/:provider", api.OAuth.Authorize)         // 跳转到身份提供方单点登录
+		adminGroup.GET("/oauth/:provider/callback", api.OAuth.Callback) // 身份提供方回调，返回登录结果
+		adminGroup.POST("/password/forgot", api.Password.Forgot)        // 找回密码，发送重置链接到邮箱
+		adminGroup.POST("/password/reset", api.Password.Reset)          // 使用重置链接中的 token 设置新密码
+		// 会话、两步验证和 API Key 管理只允许用户本人登录后操作
+		sessionGroup := adminGroup.Group("", jwtAuth, accessLog)
+		{
+			sessionGroup.POST("/logout", api.Auth.Logout)        // 退出当前会话
+			sessionGroup.POST("/logout/all", api.Auth.LogoutAll) // 退出所有会话
+			// 修改密码，注销所有会话并返回新 token
+			sessionGroup.POST("/password/change", appMiddlewares.OmitRequest(), appMiddlewares.OmitResponse(), api.Password.Change)
+			// 当前用户的两步验证设置
+			sessionGroup.POST("/mfa/setup", appMiddlewares.OmitResponse(), api.MFA.Setup)     // 获取 TOTP 密钥
+			sessionGroup.POST("/mfa/confirm", appMiddlewares.OmitResponse(), api.MFA.Confirm) // 确认并启用，返回恢复码
+			sessionGroup.POST("/mfa/disable", api.MFA.Disable)                                // 关闭两步验证
+			// 当前用户的 API Key
+			sessionGroup.GET("/apikey", api.APIKey.List)                                          // 查询 API Key 列表
+			sessionGroup.POST("/apikey/create", appMiddlewares.OmitResponse(), api.APIKey.Create) // 创建 API Key，密钥只返回一次
+			sessionGroup.POST("/apikey/revoke", api.APIKey.Revoke)                                // 吊销 API Key
+		}
+		// 用户和角色操作需要认证和权限中间件，支持 Authorization: ApiKey xxx
+		adminGroup.Use(apiKeyAuth, jwtAuth, accessLog, permission)
 		// 用户管理
 		userGroup := adminGroup.Group("/user")
 		{
-			userGroup.GET("", api.User.List)             // 查询用户列表
-			userGroup.POST("/create", api.User.Create)   // 创建用户
-			userGroup.GET("/view", api.User.View)        // 查看用户
-			userGroup.PUT("/edit", api.User.Edit)        // 编辑用户
-			userGroup.DELETE("/delete", api.User.Delete) // 删除用户
+			userGroup.GET("", api.User.List)                                         // 查询用户列表
+			userGroup.POST("/create", appMiddlewares.OmitRequest(), api.User.Create) // 创建用户
+			userGroup.GET("/view", api.User.View)                                    // 查看用户
+			userGroup.PUT("/edit", appMiddlewares.OmitRequest(), api.User.Edit)      // 编辑用户，密码为空时不修改
+			userGroup.DELETE("/delete", api.User.Delete)                             // 删除用户
+			userGroup.POST("/kick", api.User.Kick)                                   // 踢出用户的所有会话
+			userGroup.POST("/unlock", api.User.Unlock)                               // 解锁登录失败被锁定的账号
 		}
 
 		// 角色管理
@@ -60,6 +88,7 @@ func (a *Route) Routes(ctx context.Context, g *gin.Engine) error {
 			roleGroup.DELETE("/delete", api.Role.Delete)   // 删除角色
 			roleGroup.GET("/auth/list", api.Role.AuthList) // 角色权限列表
 			roleGroup.POST("/auth", api.Role.Auth)         // 授权权限
+			roleGroup.POST("/mfa", api.Role.MFA)           // 设置是否要求两步验证（仅超级管理员）
 		}
 
 		// 菜单管理
@@ -71,6 +100,19 @@ func (a *Route) Routes(ctx context.Context, g *gin.Engine) error {
 			menuGroup.PUT("/edit", api.Menu.Edit)        // 编辑菜单
 			menuGroup.DELETE("/delete", api.Menu.Delete) // 删除菜单
 		}
+
+		// 队列管理
+		queueGroup := adminGroup.Group("/queue")
+		{
+			queueGroup.GET("", api.Queue.List)                          // 查询任务列表
+			queueGroup.GET("/view", api.Queue.View)                     // 查看任务
+			queueGroup.DELETE("/delete", api.Queue.Delete)              // 删除任务
+			queueGroup.DELETE("/purge", api.Queue.Purge)                // 清空队列
+			queueGroup.GET("/failed", api.Queue.FailedList)             // 查询死信列表
+			queueGroup.GET("/failed/view", api.Queue.FailedView)        // 查看死信
+			queueGroup.POST("/failed/retry", api.Queue.FailedRetry)     // 重试死信
+			queueGroup.DELETE("/failed/delete", api.Queue.FailedDelete) // 删除死信
+		}
 	}
 	return nil
 }
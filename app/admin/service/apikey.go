@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"regexp"
+	"time"
+	"wangzhiqiang/skeleton/app/admin/models"
+	"wangzhiqiang/skeleton/app/admin/types"
+	appModels "wangzhiqiang/skeleton/app/models"
+	"wangzhiqiang/skeleton/pkg/app"
+	"wangzhiqiang/skeleton/pkg/jwts"
+)
+
+const (
+	apiKeyPrefixLen      = 11          // 前缀长度，格式为 sk_ 加 8 位十六进制
+	apiKeyLastUsedPeriod = time.Minute // 最近使用时间的更新间隔，避免每次请求都写库
+)
+
+var (
+	ErrInvalidAPIKey = errors.New("API Key 无效或已过期")
+	scopePattern     = regexp.MustCompile(`^(\*|[a-z_]+:(\*|read|write))$`)
+)
+
+type APIKeyService struct {
+}
+
+// Create 创建 API Key，完整密钥只在创建时返回
+func (a *APIKeyService) Create(ctx context.Context, uid uint, req *types.APIKeyCreateReq, ip string) (*types.APIKeyCreateResp, error) {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range req.Scopes {
+		if !scopePattern.MatchString(scope) {
+			return nil, fmt.Errorf("授权范围格式错误: %s", scope)
+		}
+	}
+	if req.ExpiresIn < 0 {
+		return nil, fmt.Errorf("有效期不能小于 0")
+	}
+	buf := make([]byte, 36)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	raw := hex.EncodeToString(buf)
+	key := "sk_" + raw[:8] + "_" + raw[8:]
+	apiKey := &models.SysAPIKey{
+		UserID:  uid,
+		Name:    req.Name,
+		Prefix:  key[:apiKeyPrefixLen],
+		KeyHash: hashAPIKey(key),
+		Scopes:  req.Scopes,
+	}
+	if req.ExpiresIn > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresIn)
+		apiKey.ExpiresAt = &expiresAt
+	}
+	err = apps.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(apiKey).Error; err != nil {
+			return err
+		}
+		return tx.Create(&appModels.SysAuditLog{
+			Action:     appModels.AuditAPIKeyCreate,
+			UserID:     uid,
+			OperatorID: uid,
+			Ip:         ip,
+			Detail:     "prefix=" + apiKey.Prefix,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &types.APIKeyCreateResp{Key: key, APIKey: apiKey}, nil
+}
+
+// List 用户的 API Key 列表
+func (a *APIKeyService) List(ctx context.Context, uid uint) ([]*models.SysAPIKey, error) {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys := []*models.SysAPIKey{}
+	err = apps.DB.Where("user_id = ?", uid).Order("id DESC").Find(&keys).Error
+	return keys, err
+}
+
+// Revoke 吊销用户的 API Key
+func (a *APIKeyService) Revoke(ctx context.Context, uid uint, req *types.IDReq, ip string) error {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return err
+	}
+	return apps.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.SysAPIKey{}).
+			Where("id = ? AND user_id = ? AND revoked_at IS NULL", req.ID, uid).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("API Key 不存在或已吊销")
+		}
+		return tx.Create(&appModels.SysAuditLog{
+			Action:     appModels.AuditAPIKeyRevoke,
+			UserID:     uid,
+			OperatorID: uid,
+			Ip:         ip,
+			Detail:     fmt.Sprintf("id=%d", req.ID),
+		}).Error
+	})
+}
+
+// Authenticate 校验 API Key，返回所属用户信息和授权范围，用户被删除后 API Key 随之失效
+func (a *APIKeyService) Authenticate(ctx context.Context, key string) (*jwts.Claims, []string, error) {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(key) <= apiKeyPrefixLen {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	var apiKey models.SysAPIKey
+	if err := apps.DB.Where("prefix = ?", key[:apiKeyPrefixLen]).Limit(1).Find(&apiKey).Error; err != nil {
+		return nil, nil, err
+	}
+	if apiKey.ID == 0 || subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(hashAPIKey(key))) != 1 || !apiKey.Active() {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	var user models.SysUser
+	if err := apps.DB.Limit(1).Find(&user, apiKey.UserID).Error; err != nil {
+		return nil, nil, err
+	}
+	if user.ID == 0 {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	now := time.Now()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyLastUsedPeriod {
+		apps.DB.Model(&models.SysAPIKey{}).Where("id = ?", apiKey.ID).UpdateColumn("last_used_at", now)
+	}
+	claims := &jwts.Claims{UID: user.ID, Name: user.Name, TokenType: jwts.APIKeyTokenType}
+	claims.ID = apiKey.Prefix
+	return claims, apiKey.Scopes, nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
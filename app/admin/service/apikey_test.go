@@ -0,0 +1,84 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"wangzhiqiang/skeleton/app/admin/models"
+	"wangzhiqiang/skeleton/app/admin/types"
+	"wangzhiqiang/skeleton/pkg/app"
+	"wangzhiqiang/skeleton/pkg/jwts"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyService_Authenticate(t *testing.T) {
+	ctx := newTestApps(t)
+	apps, err := app.GetApps(ctx)
+	require.NoError(t, err)
+	user := models.SysUser{Email: "admin@example.com", Name: "admin"}
+	require.NoError(t, apps.DB.Create(&user).Error)
+
+	s := new(APIKeyService)
+	_, err = s.Create(ctx, user.ID, &types.APIKeyCreateReq{Name: "bad", Scopes: []string{"user:delete"}}, "")
+	assert.ErrorContains(t, err, "授权范围格式错误")
+
+	resp, err := s.Create(ctx, user.ID, &types.APIKeyCreateReq{Name: "ci", Scopes: []string{"user:read"}, ExpiresIn: 30}, "")
+	require.NoError(t, err)
+	key := resp.Key
+	assert.True(t, strings.HasPrefix(key, resp.APIKey.Prefix))
+
+	// 只保存前缀和哈希
+	var stored models.SysAPIKey
+	require.NoError(t, apps.DB.First(&stored, resp.APIKey.ID).Error)
+	assert.Equal(t, key[:apiKeyPrefixLen], stored.Prefix)
+	assert.Equal(t, hashAPIKey(key), stored.KeyHash)
+
+	claims, scopes, err := s.Authenticate(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UID)
+	assert.Equal(t, jwts.APIKeyTokenType, claims.TokenType)
+	assert.Equal(t, []string{"user:read"}, scopes)
+	require.NoError(t, apps.DB.First(&stored, resp.APIKey.ID).Error)
+	assert.NotNil(t, stored.LastUsedAt)
+
+	// 前缀相同但密钥不同、长度不足
+	for _, invalid := range []string{stored.Prefix + "_guess", stored.Prefix, ""} {
+		_, _, err = s.Authenticate(ctx, invalid)
+		assert.ErrorIs(t, err, ErrInvalidAPIKey, invalid)
+	}
+
+	// 过期
+	require.NoError(t, apps.DB.Model(&stored).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	_, _, err = s.Authenticate(ctx, key)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestAPIKeyService_Revoke(t *testing.T) {
+	ctx := newTestApps(t)
+	apps, err := app.GetApps(ctx)
+	require.NoError(t, err)
+	user := models.SysUser{Email: "admin@example.com", Name: "admin"}
+	require.NoError(t, apps.DB.Create(&user).Error)
+
+	s := new(APIKeyService)
+	resp, err := s.Create(ctx, user.ID, &types.APIKeyCreateReq{Name: "ci", Scopes: []string{"*"}}, "")
+	require.NoError(t, err)
+	_, _, err = s.Authenticate(ctx, resp.Key)
+	require.NoError(t, err)
+
+	// 只能吊销自己的 API Key
+	assert.Error(t, s.Revoke(ctx, user.ID+1, &types.IDReq{ID: resp.APIKey.ID}, ""))
+	require.NoError(t, s.Revoke(ctx, user.ID, &types.IDReq{ID: resp.APIKey.ID}, ""))
+	_, _, err = s.Authenticate(ctx, resp.Key)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	assert.Error(t, s.Revoke(ctx, user.ID, &types.IDReq{ID: resp.APIKey.ID}, ""))
+
+	// 用户删除后 API Key 失效
+	other, err := s.Create(ctx, user.ID, &types.APIKeyCreateReq{Name: "other", Scopes: []string{"*"}}, "")
+	require.NoError(t, err)
+	require.NoError(t, apps.DB.Delete(&user).Error)
+	_, _, err = s.Authenticate(ctx, other.Key)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
@@ -2,12 +2,14 @@ package service
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"gorm.io/gorm"
 	"time"
 	"wangzhiqiang/skeleton/app/admin/models"
 	"wangzhiqiang/skeleton/app/admin/types"
 	"wangzhiqiang/skeleton/pkg/app"
 	"wangzhiqiang/skeleton/pkg/cryptox"
+	"wangzhiqiang/skeleton/pkg/jwts"
 )
 
 type AuthService struct {
@@ -18,30 +20,134 @@ func (s *AuthService) Login(ctx context.Context, req *types.LoginReq) (*types.Lo
 	if err != nil {
 		return nil, err
 	}
+	db := apps.DB
+	// 检查账号和 IP 是否被限制登录，并预先计为一次失败
+	limiter := &loginLimiter{db: db, cfg: apps.Config.System.Login}
+	attempt, err := limiter.acquire(req.Email, req.IP)
+	if err != nil {
+		return nil, err
+	}
 	//查询用户信息
 	var user models.SysUser
-	db := apps.DB
 	if err := db.Model(models.SysUser{}).Where(models.SysUser{Email: req.Email}).First(&user).Error; err != nil {
-		return nil, fmt.Errorf("邮箱不存在")
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		cryptox.HashVerify(req.Password, dummyHash(apps.Config.System.Password))
 	}
-	// 验证密码
-	if !cryptox.HashVerify(req.Password, user.Password) {
-		return nil, fmt.Errorf("密码错误")
+	// 验证密码，邮箱不存在和密码错误返回相同的错误
+	if user.ID == 0 || !verifyPassword(apps, &user, req.Password) {
+		if err := limiter.fail(attempt, user.ID); err != nil {
+			apps.Logger.Errorf("[Login] record failed attempt error: %v", err)
+		}
+		return nil, ErrInvalidCredentials
 	}
-	jwt := apps.JWT
-	//生成JWT token
-	token, err := jwt.BuildAccessToken(&user)
+	if err := limiter.release(attempt); err != nil {
+		return nil, err
+	}
+	// 密码正确但仍需两步验证时不能清除失败次数，否则可在每轮猜测验证码前重新登录解除锁定
+	return s.complete(ctx, apps, &user, req.IP, req.UserAgent)
+}
+
+// complete 身份验证通过后完成登录
+//
+//	启用两步验证或角色要求两步验证时，先返回等待验证的 token
+func (s *AuthService) complete(ctx context.Context, apps app.Apps, user *models.SysUser, ip, userAgent string) (*types.LoginResp, error) {
+	required, err := mfaRequired(apps.DB, user.ID)
 	if err != nil {
 		return nil, err
 	}
-	refreshToken, err := jwt.BuildRefreshToken(&user)
+	if user.MFAEnabled || required {
+		token, err := apps.JWT.BuildMFAPendingToken(user, mfaPendingExpiration)
+		if err != nil {
+			return nil, err
+		}
+		return &types.LoginResp{MFARequired: true, MFAEnroll: !user.MFAEnabled, MFAToken: token}, nil
+	}
+	return s.signIn(ctx, apps, user, ip, userAgent)
+}
+
+// LoginMFA 提交两步验证码完成登录，尚未绑定时校验通过即完成绑定并返回恢复码
+func (s *AuthService) LoginMFA(ctx context.Context, req *types.MFALoginReq) (*types.LoginResp, error) {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.pendingUser(apps, req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+	limiter := &loginLimiter{db: apps.DB, cfg: apps.Config.System.Login}
+	attempt, err := limiter.acquire(user.Email, req.IP)
+	if err != nil {
+		return nil, err
+	}
+	var codes []string
+	if user.MFAEnabled {
+		err = verifyMFA(apps, user, req.Code)
+	} else {
+		codes, err = confirmMFA(apps, user, req.Code)
+	}
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if err := limiter.fail(attempt, user.ID); err != nil {
+				apps.Logger.Errorf("[Login] record failed attempt error: %v", err)
+			}
+		} else if err := limiter.release(attempt); err != nil {
+			apps.Logger.Errorf("[Login] release attempt error: %v", err)
+		}
+		return nil, err
+	}
+	resp, err := s.signIn(ctx, apps, user, req.IP, req.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+	resp.RecoveryCodes = codes
+	return resp, nil
+}
+
+// LoginMFASetup 角色要求两步验证但尚未绑定时，登录过程中获取 TOTP 密钥
+func (s *AuthService) LoginMFASetup(ctx context.Context, req *types.MFATokenReq) (*types.MFASetupResp, error) {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.pendingUser(apps, req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+	return setupMFA(apps, user)
+}
+
+// pendingUser 解析等待两步验证的 token 并查询用户
+func (s *AuthService) pendingUser(apps app.Apps, token string) (*models.SysUser, error) {
+	claims, err := apps.JWT.Parse(token, jwts.RequireTokenType(jwts.MFAPendingTokenType))
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	var user models.SysUser
+	if err := apps.DB.First(&user, claims.UID).Error; err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	return &user, nil
+}
+
+// signIn 创建登录会话并返回 token 和菜单，完成全部验证后才清除账号的登录失败次数
+func (s *AuthService) signIn(ctx context.Context, apps app.Apps, user *models.SysUser, ip, userAgent string) (*types.LoginResp, error) {
+	limiter := &loginLimiter{db: apps.DB, cfg: apps.Config.System.Login}
+	if err := limiter.reset(user.Email); err != nil {
+		return nil, err
+	}
+	jwt := apps.JWT
+	//创建登录会话并生成JWT token
+	token, refreshToken, err := jwt.CreateSession(user, ip, userAgent)
 	if err != nil {
 		return nil, err
 	}
 	// 更新用户登录信息
-	db.Model(models.SysUser{}).Where("id = ?", user.ID).Updates(&models.SysUser{
+	apps.DB.Model(models.SysUser{}).Where("id = ?", user.ID).Updates(&models.SysUser{
 		LastLogin: time.Now(),
-		LastIp:    req.IP,
+		LastIp:    ip,
 	})
 	//获取当前用户菜单权限
 	menus, _ := new(UserService).GetUserMenus(ctx, user.ID)
@@ -52,6 +158,7 @@ func (s *AuthService) Login(ctx context.Context, req *types.LoginReq) (*types.Lo
 		Menus:        menus,
 	}, nil
 }
+
 func (s *AuthService) Refresh(ctx context.Context, req *types.RefreshTokenReq) (*types.LoginResp, error) {
 	apps, err := app.GetApps(ctx)
 	if err != nil {
@@ -69,3 +176,22 @@ func (s *AuthService) Refresh(ctx context.Context, req *types.RefreshTokenReq) (
 		ExpiresIn:    jwts.GetAccessExpiresIn(),
 	}, nil
 }
+
+// Logout 注销当前会话
+func (s *AuthService) Logout(ctx context.Context, sid string) error {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return err
+	}
+	return apps.JWT.RevokeSession(sid)
+}
+
+// LogoutAll 注销用户的所有会话
+func (s *AuthService) LogoutAll(ctx context.Context, uid uint) error {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = apps.JWT.RevokeUserSessions(uid)
+	return err
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"wangzhiqiang/skeleton/app/admin/models"
+	"wangzhiqiang/skeleton/app/admin/types"
+	appModels "wangzhiqiang/skeleton/app/models"
+	"wangzhiqiang/skeleton/config"
+	"wangzhiqiang/skeleton/pkg/app"
+	"wangzhiqiang/skeleton/pkg/cryptox"
+	"wangzhiqiang/skeleton/pkg/database"
+	"wangzhiqiang/skeleton/pkg/jwts"
+	"wangzhiqiang/skeleton/pkg/logger"
+	"wangzhiqiang/skeleton/pkg/totp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// testEncryptionKey 测试使用的字段加密密钥
+var testEncryptionKey = base64.StdEncoding.EncodeToString(make([]byte, 32))
+
+// newTestApps 使用内存数据库创建登录所需的依赖
+func newTestApps(t *testing.T) context.Context {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{Logger: gormLogger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.SysUser{}, &models.SysRole{}, &models.SysLoginAttempt{},
+		&models.SysMFARecoveryCode{}, &models.SysPasswordReset{}, &models.SysAPIKey{}, &appModels.SysAuditLog{}))
+	keyring, err := cryptox.NewKeyring(&cryptox.EncryptionConfig{Keys: []*cryptox.EncryptionKey{{Version: "1", Key: testEncryptionKey}}})
+	require.NoError(t, err)
+	database.SetKeyring(keyring)
+	jwtCfg := &jwts.Config{Secret: "test-secret", Expiration: 3600, RefreshExpiration: 7200}
+	j, err := jwts.NewJWT(jwtCfg)
+	require.NoError(t, err)
+	log, err := logger.NewLogger(&logger.Config{Level: "error"})
+	require.NoError(t, err)
+	cfg := &config.Config{JWT: jwtCfg, System: &config.SystemConfig{
+		Login:    &config.LoginConfig{MaxAttempts: 3, IPMaxAttempts: 100, Window: 900, LockDuration: 1800},
+		Password: &config.PasswordConfig{ResetExpiration: 1800, ResetURL: "http://127.0.0.1/reset"},
+	}}
+	apps := app.Apps{DB: db, JWT: j, Logger: log, Config: cfg}
+	return context.WithValue(context.Background(), app.ContextAppKey, apps)
+}
+
+func TestAuthService_PasswordLoginKeepsMFAFailures(t *testing.T) {
+	ctx := newTestApps(t)
+	apps, err := app.GetApps(ctx)
+	require.NoError(t, err)
+	hash, err := cryptox.HashMake("123456")
+	require.NoError(t, err)
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	user := models.SysUser{Email: "admin@example.com", Name: "admin", Password: hash, MFAEnabled: true, MFASecret: secret}
+	require.NoError(t, apps.DB.Create(&user).Error)
+
+	s := new(AuthService)
+	login := &types.LoginReq{Email: user.Email, Password: "123456", IP: "127.0.0.1"}
+	guess := func() error {
+		resp, err := s.Login(ctx, login)
+		if err != nil {
+			return err
+		}
+		require.True(t, resp.MFARequired)
+		_, err = s.LoginMFA(ctx, &types.MFALoginReq{MFAToken: resp.MFAToken, Code: "invalid", IP: login.IP})
+		return err
+	}
+	// 每次猜测验证码前重新用密码登录，失败次数仍然累计
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(t, guess(), ErrInvalidMFACode)
+	}
+	_, err = s.Login(ctx, login)
+	assert.ErrorContains(t, err, "账号已锁定")
+}
+
+func TestAuthService_ConcurrentFailures(t *testing.T) {
+	ctx := newTestApps(t)
+	apps, err := app.GetApps(ctx)
+	require.NoError(t, err)
+	// 内存数据库使用单个连接，避免并发事务返回 database is locked
+	sqlDB, err := apps.DB.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	hash, err := cryptox.HashMake("123456")
+	require.NoError(t, err)
+	user := models.SysUser{Email: "admin@example.com", Name: "admin", Password: hash}
+	require.NoError(t, apps.DB.Create(&user).Error)
+
+	// 并发提交错误密码，超过上限的请求在校验密码前被拒绝
+	s := new(AuthService)
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.Login(ctx, &types.LoginReq{Email: user.Email, Password: "wrong", IP: "127.0.0.1"})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	verified := 0
+	for err := range errs {
+		if errors.Is(err, ErrInvalidCredentials) {
+			verified++
+			continue
+		}
+		assert.ErrorContains(t, err, "账号已锁定")
+	}
+	assert.Equal(t, 3, verified)
+
+	var attempt models.SysLoginAttempt
+	require.NoError(t, apps.DB.Where("subject = ?", accountSubject(user.Email)).Take(&attempt).Error)
+	assert.Equal(t, 3, attempt.Failures)
+	assert.NotNil(t, attempt.LockedUntil)
+	var audits int64
+	apps.DB.Model(&appModels.SysAuditLog{}).Where("action = ?", appModels.AuditLoginLocked).Count(&audits)
+	assert.EqualValues(t, 1, audits)
+
+	// 密码正确的登录不计入失败次数
+	require.NoError(t, apps.DB.Where("subject = ?", accountSubject(user.Email)).Delete(&models.SysLoginAttempt{}).Error)
+	_, err = s.Login(ctx, &types.LoginReq{Email: user.Email, Password: "wrong", IP: "127.0.0.1"})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = s.Login(ctx, &types.LoginReq{Email: user.Email, Password: "123456", IP: "127.0.0.2"})
+	require.NoError(t, err)
+	var ipAttempt models.SysLoginAttempt
+	require.NoError(t, apps.DB.Where("subject = ?", ipSubject("127.0.0.2")).Take(&ipAttempt).Error)
+	assert.Equal(t, 0, ipAttempt.Failures)
+}
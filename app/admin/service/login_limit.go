@@ -0,0 +1,193 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"wangzhiqiang/skeleton/app/admin/models"
+	appModels "wangzhiqiang/skeleton/app/models"
+	"wangzhiqiang/skeleton/config"
+	"wangzhiqiang/skeleton/pkg/cryptox"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("邮箱或密码错误")
+	ErrTooManyAttempts    = errors.New("登录失败次数过多，请稍后再试")
+)
+
+var (
+	dummyOnce sync.Once
+	dummy     string
+)
+
+// dummyHash 邮箱不存在时也校验一次密码，避免通过响应时间判断账号是否存在
+//
+//	使用当前配置的算法生成，与真实用户的校验耗时一致
+func dummyHash(cfg *config.PasswordConfig) string {
+	dummyOnce.Do(func() {
+		hasher, err := cfg.Hash.Hasher()
+		if err == nil {
+			dummy, err = hasher.Hash("dummy-password")
+		}
+		if err != nil {
+			dummy, _ = cryptox.HashMake("dummy-password")
+		}
+	})
+	return dummy
+}
+
+// loginLimiter 登录失败限制，按账号和 IP 分别计数
+type loginLimiter struct {
+	db  *gorm.DB
+	cfg *config.LoginConfig
+}
+
+func accountSubject(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipSubject(ip string) string {
+	return "ip:" + ip
+}
+
+// loginAttempt 一次已预先计数的登录尝试
+type loginAttempt struct {
+	email         string
+	ip            string
+	accountLocked bool // 本次尝试达到账号失败上限
+	ipLocked      bool // 本次尝试达到 IP 失败上限
+	failures      int  // 账号窗口内的尝试次数
+	ipFailures    int  // IP 窗口内的尝试次数
+}
+
+// check 检查账号和 IP 是否被锁定，以及距上次失败是否已过等待时间，不计数
+//
+//	不存在的邮箱同样计数和锁定，避免通过锁定提示判断账号是否存在
+func (l *loginLimiter) check(email, ip string) error {
+	var attempts []models.SysLoginAttempt
+	if err := l.db.Where("subject IN ?", []string{accountSubject(email), ipSubject(ip)}).Find(&attempts).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for i := range attempts {
+		if err := l.verify(&attempts[i], now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verify 检查单个计数对象是否允许再次尝试
+func (l *loginLimiter) verify(a *models.SysLoginAttempt, now time.Time) error {
+	if a.LockedUntil != nil && now.Before(*a.LockedUntil) {
+		if strings.HasPrefix(a.Subject, "ip:") {
+			return ErrTooManyAttempts
+		}
+		return fmt.Errorf("账号已锁定，请 %d 分钟后再试或联系管理员解锁", int(math.Ceil(a.LockedUntil.Sub(now).Minutes())))
+	}
+	if a.Failures == 0 || now.Sub(a.FirstFailedAt) > l.cfg.GetWindow() {
+		return nil
+	}
+	if wait := a.LastFailedAt.Add(l.cfg.GetDelay(a.Failures)).Sub(now); wait > 0 {
+		return fmt.Errorf("尝试过于频繁，请 %d 秒后再试", int(math.Ceil(wait.Seconds())))
+	}
+	return nil
+}
+
+// acquire 校验凭证前检查限制并预先计为一次失败，凭证正确时调用 release 撤销
+//
+//	检查和计数在同一事务中对计数行加锁完成，并发请求逐个计数，不能同时绕过等待时间和锁定
+func (l *loginLimiter) acquire(email, ip string) (*loginAttempt, error) {
+	a := &loginAttempt{email: email, ip: ip}
+	err := l.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if a.accountLocked, a.failures, err = l.incr(tx, accountSubject(email), l.cfg.MaxAttempts); err != nil {
+			return err
+		}
+		a.ipLocked, a.ipFailures, err = l.incr(tx, ipSubject(ip), l.cfg.IPMaxAttempts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// incr 锁定计数行并检查限制，通过后失败次数加一，窗口过期或锁定结束后重新计数，返回本次是否触发锁定
+func (l *loginLimiter) incr(tx *gorm.DB, subject string, limit int) (locked bool, failures int, err error) {
+	now := time.Now()
+	// 先插入空计数行，保证后续加锁读取时行已存在
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.SysLoginAttempt{Subject: subject, FirstFailedAt: now, LastFailedAt: now}).Error; err != nil {
+		return false, 0, err
+	}
+	var a models.SysLoginAttempt
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("subject = ?", subject).Take(&a).Error; err != nil {
+		return false, 0, err
+	}
+	if err := l.verify(&a, now); err != nil {
+		return false, 0, err
+	}
+	if a.Failures == 0 || now.Sub(a.FirstFailedAt) > l.cfg.GetWindow() || (a.LockedUntil != nil && !now.Before(*a.LockedUntil)) {
+		a.Failures = 0
+		a.FirstFailedAt = now
+		a.LockedUntil = nil
+	}
+	a.Failures++
+	a.LastFailedAt = now
+	if a.Failures >= limit && a.LockedUntil == nil {
+		until := now.Add(l.cfg.GetLockDuration())
+		a.LockedUntil = &until
+		locked = true
+	}
+	return locked, a.Failures, tx.Save(&a).Error
+}
+
+// fail 凭证错误，预先记录的失败保留，达到上限时写入审计日志
+func (l *loginLimiter) fail(a *loginAttempt, uid uint) error {
+	if a.accountLocked {
+		if err := l.audit(appModels.AuditLoginLocked, uid, a.ip, fmt.Sprintf("email=%s failures=%d", a.email, a.failures)); err != nil {
+			return err
+		}
+	}
+	if a.ipLocked {
+		return l.audit(appModels.AuditIPBlocked, 0, a.ip, fmt.Sprintf("email=%s failures=%d", a.email, a.ipFailures))
+	}
+	return nil
+}
+
+// release 凭证正确，撤销预先记录的失败，本次触发的锁定一并解除
+func (l *loginLimiter) release(a *loginAttempt) error {
+	return l.db.Transaction(func(tx *gorm.DB) error {
+		for _, s := range []struct {
+			subject string
+			locked  bool
+		}{{accountSubject(a.email), a.accountLocked}, {ipSubject(a.ip), a.ipLocked}} {
+			updates := map[string]any{"failures": gorm.Expr("failures - 1")}
+			if s.locked {
+				updates["locked_until"] = nil
+			}
+			if err := tx.Model(&models.SysLoginAttempt{}).
+				Where("subject = ? AND failures > 0", s.subject).
+				Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// reset 登录成功或管理员解锁后清除账号的失败计数，IP 计数不清除
+func (l *loginLimiter) reset(email string) error {
+	return l.db.Where("subject = ?", accountSubject(email)).Delete(&models.SysLoginAttempt{}).Error
+}
+
+// audit 写入审计日志
+func (l *loginLimiter) audit(action string, uid uint, ip, detail string) error {
+	return l.db.Create(&appModels.SysAuditLog{Action: action, UserID: uid, Ip: ip, Detail: detail}).Error
+}
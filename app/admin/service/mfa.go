@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+	"wangzhiqiang/skeleton/app/admin/models"
+	"wangzhiqiang/skeleton/app/admin/types"
+	appModels "wangzhiqiang/skeleton/app/models"
+	"wangzhiqiang/skeleton/pkg/app"
+	"wangzhiqiang/skeleton/pkg/totp"
+)
+
+const (
+	mfaPendingExpiration = 300 // 等待两步验证的 token 有效期（秒）
+	mfaSkew              = 1   // 允许前后各一个周期的时钟偏差
+	recoveryCodeCount    = 10  // 恢复码数量
+)
+
+var (
+	ErrInvalidMFACode  = errors.New("验证码错误")
+	ErrInvalidMFAToken = errors.New("两步验证已过期，请重新登录")
+)
+
+type MFAService struct {
+}
+
+// Setup 生成新的 TOTP 密钥，确认前不会启用
+func (m *MFAService) Setup(ctx context.Context, uid uint) (*types.MFASetupResp, error) {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var user models.SysUser
+	if err := apps.DB.First(&user, uid).Error; err != nil {
+		return nil, err
+	}
+	return setupMFA(apps, &user)
+}
+
+// Confirm 校验验证码并启用两步验证，返回恢复码
+func (m *MFAService) Confirm(ctx context.Context, uid uint, code string) (*types.MFAConfirmResp, error) {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var user models.SysUser
+	if err := apps.DB.First(&user, uid).Error; err != nil {
+		return nil, err
+	}
+	codes, err := confirmMFA(apps, &user, code)
+	if err != nil {
+		return nil, err
+	}
+	return &types.MFAConfirmResp{RecoveryCodes: codes}, nil
+}
+
+// Disable 关闭两步验证，所属角色要求两步验证时不能关闭
+func (m *MFAService) Disable(ctx context.Context, uid uint, code string) error {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return err
+	}
+	var user models.SysUser
+	if err := apps.DB.First(&user, uid).Error; err != nil {
+		return err
+	}
+	if !user.MFAEnabled {
+		return nil
+	}
+	required, err := mfaRequired(apps.DB, user.ID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return fmt.Errorf("所属角色要求两步验证，无法关闭")
+	}
+	if err := verifyMFA(apps, &user, code); err != nil {
+		return err
+	}
+	return apps.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.SysUser{}).Where("id = ?", user.ID).Updates(map[string]any{
+			"mfa_enabled":   false,
+			"mfa_secret":    "",
+			"mfa_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.SysMFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&appModels.SysAuditLog{Action: appModels.AuditMFADisabled, UserID: user.ID, OperatorID: user.ID}).Error
+	})
+}
+
+// mfaRequired 用户的角色是否要求两步验证
+func mfaRequired(db *gorm.DB, uid uint) (bool, error) {
+	var count int64
+	roleIDs := db.Table("sys_user_roles").Select("sys_role_id").Where("sys_user_id = ?", uid)
+	err := db.Model(&models.SysRole{}).
+		Where("id IN (?) AND require_mfa = ?", roleIDs, true).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// setupMFA 生成并保存待确认的 TOTP 密钥
+func setupMFA(apps app.Apps, user *models.SysUser) (*types.MFASetupResp, error) {
+	if user.MFAEnabled {
+		return nil, fmt.Errorf("已启用两步验证，请先关闭")
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	// 按结构体更新，密钥经过加密序列化器写入
+	if err := apps.DB.Model(&models.SysUser{}).Where("id = ?", user.ID).Updates(&models.SysUser{MFASecret: secret}).Error; err != nil {
+		return nil, err
+	}
+	return &types.MFASetupResp{Secret: secret, URI: totp.URI(apps.Config.JWT.Issuer, user.Email, secret)}, nil
+}
+
+// confirmMFA 校验待确认密钥的验证码，启用两步验证并生成恢复码
+func confirmMFA(apps app.Apps, user *models.SysUser, code string) ([]string, error) {
+	if user.MFAEnabled {
+		return nil, fmt.Errorf("已启用两步验证")
+	}
+	if user.MFASecret == "" {
+		return nil, fmt.Errorf("请先获取两步验证密钥")
+	}
+	step, ok := totp.Validate(user.MFASecret, code, time.Now(), mfaSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+	codes := make([]string, recoveryCodeCount)
+	records := make([]*models.SysMFARecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := hex.EncodeToString(buf)
+		codes[i] = raw[:5] + "-" + raw[5:]
+		records[i] = &models.SysMFARecoveryCode{UserID: user.ID, CodeHash: hashRecoveryCode(codes[i])}
+	}
+	err := apps.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.SysUser{}).Where("id = ?", user.ID).Updates(map[string]any{
+			"mfa_enabled":   true,
+			"mfa_last_step": step,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.SysMFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&records).Error; err != nil {
+			return err
+		}
+		return tx.Create(&appModels.SysAuditLog{Action: appModels.AuditMFAEnabled, UserID: user.ID, OperatorID: user.ID}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.MFAEnabled = true
+	return codes, nil
+}
+
+// verifyMFA 校验 TOTP 验证码或恢复码，同一周期的验证码和恢复码都只能使用一次
+func verifyMFA(apps app.Apps, user *models.SysUser, code string) error {
+	db := apps.DB
+	if step, ok := totp.Validate(user.MFASecret, code, time.Now(), mfaSkew); ok {
+		result := db.Model(&models.SysUser{}).
+			Where("id = ? AND mfa_last_step < ?", user.ID, step).
+			Update("mfa_last_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+	result := db.Model(&models.SysMFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidMFACode
+	}
+	return db.Create(&appModels.SysAuditLog{Action: appModels.AuditMFARecovery, UserID: user.ID, OperatorID: user.ID}).Error
+}
+
+// hashRecoveryCode 恢复码忽略大小写和空白
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
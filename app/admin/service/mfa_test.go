@@ -0,0 +1,38 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"wangzhiqiang/skeleton/app/admin/models"
+	"wangzhiqiang/skeleton/pkg/app"
+	"wangzhiqiang/skeleton/pkg/totp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMFAService_SecretEncrypted(t *testing.T) {
+	ctx := newTestApps(t)
+	apps, err := app.GetApps(ctx)
+	require.NoError(t, err)
+	user := models.SysUser{Email: "admin@example.com", Name: "admin"}
+	require.NoError(t, apps.DB.Create(&user).Error)
+
+	s := new(MFAService)
+	resp, err := s.Setup(ctx, user.ID)
+	require.NoError(t, err)
+
+	// 数据库中只保存密文
+	var raw string
+	require.NoError(t, apps.DB.Table("sys_users").Where("id = ?", user.ID).Pluck("mfa_secret", &raw).Error)
+	assert.True(t, strings.HasPrefix(raw, "enc:"), raw)
+	assert.NotContains(t, raw, resp.Secret)
+
+	// 读取时解密，验证码可以通过校验
+	code, err := totp.Code(resp.Secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	confirm, err := s.Confirm(ctx, user.ID, code)
+	require.NoError(t, err)
+	assert.NotEmpty(t, confirm.RecoveryCodes)
+}
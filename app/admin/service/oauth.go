@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+	"strings"
+	"wangzhiqiang/skeleton/app/admin/models"
+	"wangzhiqiang/skeleton/app/admin/types"
+	appModels "wangzhiqiang/skeleton/app/models"
+	"wangzhiqiang/skeleton/pkg/app"
+	"wangzhiqiang/skeleton/pkg/casbinx"
+	"wangzhiqiang/skeleton/pkg/oidcx"
+)
+
+var (
+	ErrInvalidOAuthState     = errors.New("登录已过期，请重新登录")
+	ErrOAuthUserNotFound     = errors.New("账号不存在，请联系管理员开通")
+	ErrOAuthIdentityConflict = errors.New("该账号已绑定其他单点登录身份，请联系管理员")
+)
+
+type OAuthService struct {
+}
+
+// Authorize 生成 state、PKCE verifier 和 nonce，返回身份提供方的授权地址
+//
+//	state 由调用方保存到会话中，回调时传入 Callback 校验
+func (o *OAuthService) Authorize(ctx context.Context, provider string) (string, *types.OAuthState, error) {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	p, err := apps.OAuth.Get(ctx, provider)
+	if err != nil {
+		return "", nil, err
+	}
+	state := &types.OAuthState{
+		State:    randomString(),
+		Verifier: oauth2.GenerateVerifier(),
+		Nonce:    randomString(),
+	}
+	return p.AuthURL(state.State, state.Verifier, state.Nonce), state, nil
+}
+
+// Callback 校验 state 并用授权码换取身份，按邮箱匹配用户后完成登录
+func (o *OAuthService) Callback(ctx context.Context, req *types.OAuthCallbackReq, saved *types.OAuthState) (*types.LoginResp, error) {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if saved == nil || subtle.ConstantTimeCompare([]byte(saved.State), []byte(req.State)) != 1 {
+		return nil, ErrInvalidOAuthState
+	}
+	if req.Error != "" {
+		return nil, fmt.Errorf("身份提供方拒绝授权：%s %s", req.Error, req.ErrorDescription)
+	}
+	p, err := apps.OAuth.Get(ctx, req.Provider)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := p.Exchange(ctx, req.Code, saved.Verifier, saved.Nonce)
+	if err != nil {
+		if errors.Is(err, oidcx.ErrEmailNotVerified) {
+			return nil, err
+		}
+		apps.Logger.Warnf("[OAuth] %s exchange error: %v", req.Provider, err)
+		return nil, fmt.Errorf("单点登录失败，请重试")
+	}
+	// 被锁定的账号同样不能通过单点登录
+	limiter := &loginLimiter{db: apps.DB, cfg: apps.Config.System.Login}
+	if err := limiter.check(identity.Email, req.IP); err != nil {
+		return nil, err
+	}
+	user, err := o.findOrCreate(apps, p, identity)
+	if err != nil {
+		return nil, err
+	}
+	if codes, ok := p.Roles(identity.Groups); ok {
+		if err := o.syncRoles(apps, user, codes); err != nil {
+			return nil, err
+		}
+	}
+	return new(AuthService).complete(ctx, apps, user, req.IP, req.UserAgent)
+}
+
+// findOrCreate 按签发者和 subject 查询已绑定的用户；未绑定时按邮箱关联已有用户并记录绑定，不存在时根据配置自动创建
+func (o *OAuthService) findOrCreate(apps app.Apps, p *oidcx.Provider, identity *oidcx.Identity) (*models.SysUser, error) {
+	var user models.SysUser
+	var binding models.SysUserIdentity
+	if err := apps.DB.Where("issuer = ? AND subject = ?", identity.Issuer, identity.Subject).Limit(1).Find(&binding).Error; err != nil {
+		return nil, err
+	}
+	if binding.ID != 0 {
+		err := apps.DB.First(&user, binding.UserID).Error
+		if err == nil {
+			return &user, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		// 绑定的用户已删除，清理后重新按邮箱关联
+		if err := apps.DB.Delete(&binding).Error; err != nil {
+			return nil, err
+		}
+	}
+	email := strings.ToLower(identity.Email)
+	if err := apps.DB.Where("LOWER(email) = ?", email).Limit(1).Find(&user).Error; err != nil {
+		return nil, err
+	}
+	action := appModels.AuditOAuthLink
+	if user.ID != 0 {
+		// 已绑定同一身份提供方其他身份的用户不再按邮箱关联
+		var count int64
+		if err := apps.DB.Model(&models.SysUserIdentity{}).
+			Where("user_id = ? AND issuer = ?", user.ID, identity.Issuer).
+			Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, ErrOAuthIdentityConflict
+		}
+	} else {
+		if !p.Config().Provision {
+			return nil, ErrOAuthUserNotFound
+		}
+		// 自动创建的用户没有密码，只能通过单点登录
+		user = models.SysUser{Email: email, Name: identity.Name}
+		if user.Name == "" {
+			user.Name = email
+		}
+		action = appModels.AuditOAuthCreate
+	}
+	err := apps.DB.Transaction(func(tx *gorm.DB) error {
+		if user.ID == 0 {
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(&models.SysUserIdentity{
+			UserID:   user.ID,
+			Provider: p.Name,
+			Issuer:   identity.Issuer,
+			Subject:  identity.Subject,
+			Email:    email,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&appModels.SysAuditLog{
+			Action: action,
+			UserID: user.ID,
+			Detail: fmt.Sprintf("provider=%s subject=%s", p.Name, identity.Subject),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// syncRoles 按用户组映射的角色编码替换用户角色并同步到 Casbin，角色未变化时不做处理
+func (o *OAuthService) syncRoles(apps app.Apps, user *models.SysUser, codes []string) error {
+	roles := []*models.SysRole{}
+	if len(codes) > 0 {
+		if err := apps.DB.Where("code IN ?", codes).Order("id").Find(&roles).Error; err != nil {
+			return err
+		}
+	}
+	var current []uint
+	if err := apps.DB.Table("sys_user_roles").Where("sys_user_id = ?", user.ID).
+		Order("sys_role_id").Pluck("sys_role_id", &current).Error; err != nil {
+		return err
+	}
+	if len(current) == len(roles) {
+		same := true
+		for i, r := range roles {
+			same = same && current[i] == r.ID
+		}
+		if same {
+			return nil
+		}
+	}
+	err := apps.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Association("Roles").Replace(roles); err != nil {
+			return err
+		}
+		return tx.Create(&appModels.SysAuditLog{
+			Action: appModels.AuditOAuthRoles,
+			UserID: user.ID,
+			Detail: "roles=" + strings.Join(codes, ","),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	user.Roles = roles
+	return casbinx.SyncUserRoles(apps.Enforcer, user)
+}
+
+// randomString 生成 32 字节随机字符串
+func randomString() string {
+	buf := make([]byte, 32)
+	_, _ = rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"net/url"
+	"time"
+	"wangzhiqiang/skeleton/app/admin/models"
+	"wangzhiqiang/skeleton/app/admin/types"
+	appModels "wangzhiqiang/skeleton/app/models"
+	"wangzhiqiang/skeleton/app/tasks"
+	"wangzhiqiang/skeleton/config"
+	"wangzhiqiang/skeleton/pkg/app"
+	"wangzhiqiang/skeleton/pkg/cryptox"
+	"wangzhiqiang/skeleton/pkg/queue"
+)
+
+const passwordForgotInterval = time.Minute // 同一账号申请找回密码的最小间隔
+
+var (
+	ErrInvalidOldPassword = errors.New("原密码错误")
+	ErrInvalidResetToken  = errors.New("重置链接无效或已过期，请重新申请")
+)
+
+type PasswordService struct {
+}
+
+// Change 修改当前用户密码，注销所有会话后返回新会话的 token
+func (p *PasswordService) Change(ctx context.Context, uid uint, req *types.PasswordChangeReq) (*types.LoginResp, error) {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var user models.SysUser
+	if err := apps.DB.First(&user, uid).Error; err != nil {
+		return nil, err
+	}
+	if user.Password == "" {
+		return nil, fmt.Errorf("尚未设置密码，请通过找回密码设置")
+	}
+	limiter := &loginLimiter{db: apps.DB, cfg: apps.Config.System.Login}
+	attempt, err := limiter.acquire(user.Email, req.IP)
+	if err != nil {
+		return nil, err
+	}
+	if !verifyPassword(apps, &user, req.OldPassword) {
+		if err := limiter.fail(attempt, user.ID); err != nil {
+			apps.Logger.Errorf("[Password] record failed attempt error: %v", err)
+		}
+		return nil, ErrInvalidOldPassword
+	}
+	if err := limiter.release(attempt); err != nil {
+		return nil, err
+	}
+	if req.NewPassword == req.OldPassword {
+		return nil, fmt.Errorf("新密码不能与原密码相同")
+	}
+	hash, err := hashPassword(apps.Config.System.Password, req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+	err = apps.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.SysUser{}).Where("id = ?", user.ID).Update("password", hash).Error; err != nil {
+			return err
+		}
+		return tx.Create(&appModels.SysAuditLog{Action: appModels.AuditPasswordChange, UserID: user.ID, OperatorID: user.ID, Ip: req.IP}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := apps.JWT.RevokeUserSessions(user.ID); err != nil {
+		return nil, err
+	}
+	return new(AuthService).signIn(ctx, apps, &user, req.IP, req.UserAgent)
+}
+
+// Forgot 申请找回密码，通过邮件队列发送重置链接
+//
+//	邮箱不存在时同样返回成功，避免通过该接口判断账号是否存在
+//	队列中只保存申请记录 ID，token 在发送时生成，不会写入任务数据、死信和日志
+func (p *PasswordService) Forgot(ctx context.Context, req *types.PasswordForgotReq) error {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return err
+	}
+	var user models.SysUser
+	if err := apps.DB.Where("email = ?", req.Email).Limit(1).Find(&user).Error; err != nil {
+		return err
+	}
+	if user.ID == 0 {
+		return nil
+	}
+	var recent int64
+	if err := apps.DB.Model(&models.SysPasswordReset{}).
+		Where("user_id = ? AND created_at > ?", user.ID, time.Now().Add(-passwordForgotInterval)).
+		Count(&recent).Error; err != nil {
+		return err
+	}
+	if recent > 0 {
+		return nil
+	}
+	// 占位 token 不保存，发送邮件时重新生成
+	token, err := newResetToken()
+	if err != nil {
+		return err
+	}
+	cfg := apps.Config.System.Password
+	now := time.Now()
+	reset := models.SysPasswordReset{
+		UserID:    user.ID,
+		TokenHash: hashResetToken(token),
+		Ip:        req.IP,
+		ExpiresAt: now.Add(cfg.GetResetExpiration()),
+	}
+	err = apps.DB.Transaction(func(tx *gorm.DB) error {
+		// 重新申请后之前的链接失效
+		if err := tx.Model(&models.SysPasswordReset{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&reset).Error
+	})
+	if err != nil {
+		return err
+	}
+	_, err = apps.Queue.Push(&PasswordResetMailTask{UserID: user.ID, ResetID: reset.ID}, 0, queue.WithQueue("mail"))
+	return err
+}
+
+// PasswordResetMailTask 发送重置密码邮件，任务数据只包含 ID，每次发送时生成新的 token
+type PasswordResetMailTask struct {
+	UserID  uint `json:"user_id"`
+	ResetID uint `json:"reset_id"`
+}
+
+// RetryPolicy 与普通邮件相同，最多尝试 5 次
+func (t *PasswordResetMailTask) RetryPolicy() queue.RetryPolicy {
+	return new(tasks.EmailTask).RetryPolicy()
+}
+
+func (t *PasswordResetMailTask) Execute(ctx context.Context, q queue.IQueue) error {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return err
+	}
+	var reset models.SysPasswordReset
+	if err := apps.DB.Where("id = ? AND user_id = ?", t.ResetID, t.UserID).Limit(1).Find(&reset).Error; err != nil {
+		return err
+	}
+	// 已使用、已重新申请或已过期的链接不再发送
+	if reset.ID == 0 || reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return nil
+	}
+	var user models.SysUser
+	if err := apps.DB.Select("id", "email").Where("id = ?", t.UserID).Limit(1).Find(&user).Error; err != nil {
+		return err
+	}
+	if user.ID == 0 {
+		return nil
+	}
+	token, err := newResetToken()
+	if err != nil {
+		return err
+	}
+	// 重试时旧 token 随之失效
+	result := apps.DB.Model(&models.SysPasswordReset{}).
+		Where("id = ? AND used_at IS NULL", reset.ID).
+		Update("token_hash", hashResetToken(token))
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	cfg := apps.Config.System.Password
+	body := fmt.Sprintf("请在 %d 分钟内打开以下链接重置密码，如非本人操作请忽略：\n%s", int(time.Until(reset.ExpiresAt).Minutes()), resetLink(cfg.ResetURL, token))
+	return tasks.SendEmail(ctx, user.Email, "重置密码", body)
+}
+
+// Reset 使用找回密码 token 重置密码，成功后注销所有会话并解除登录锁定
+func (p *PasswordService) Reset(ctx context.Context, req *types.PasswordResetReq) error {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return err
+	}
+	var reset models.SysPasswordReset
+	if err := apps.DB.Where("token_hash = ?", hashResetToken(req.Token)).Limit(1).Find(&reset).Error; err != nil {
+		return err
+	}
+	if reset.ID == 0 || reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+	var user models.SysUser
+	if err := apps.DB.First(&user, reset.UserID).Error; err != nil {
+		return ErrInvalidResetToken
+	}
+	hash, err := hashPassword(apps.Config.System.Password, req.Password)
+	if err != nil {
+		return err
+	}
+	err = apps.DB.Transaction(func(tx *gorm.DB) error {
+		// 条件更新，同一个 token 并发提交时只有一次成功
+		result := tx.Model(&models.SysPasswordReset{}).
+			Where("id = ? AND used_at IS NULL", reset.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidResetToken
+		}
+		if err := tx.Model(&models.SysUser{}).Where("id = ?", user.ID).Update("password", hash).Error; err != nil {
+			return err
+		}
+		return tx.Create(&appModels.SysAuditLog{Action: appModels.AuditPasswordReset, UserID: user.ID, OperatorID: user.ID, Ip: req.IP}).Error
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := apps.JWT.RevokeUserSessions(user.ID); err != nil {
+		return err
+	}
+	limiter := &loginLimiter{db: apps.DB, cfg: apps.Config.System.Login}
+	return limiter.reset(user.Email)
+}
+
+// hashPassword 校验密码策略并使用配置的算法生成哈希，所有写入密码的地方都需要经过这里
+func hashPassword(cfg *config.PasswordConfig, raw string) (string, error) {
+	if err := cfg.Validate(raw); err != nil {
+		return "", err
+	}
+	hasher, err := cfg.Hash.Hasher()
+	if err != nil {
+		return "", err
+	}
+	return hasher.Hash(raw)
+}
+
+// verifyPassword 校验用户密码，哈希算法或参数与当前配置不一致时使用明文重新生成哈希
+func verifyPassword(apps app.Apps, user *models.SysUser, raw string) bool {
+	hasher, err := apps.Config.System.Password.Hash.Hasher()
+	if err != nil {
+		apps.Logger.Errorf("[Password] hasher error: %v", err)
+		return cryptox.HashVerify(raw, user.Password)
+	}
+	ok, needsRehash := cryptox.HashCheck(hasher, raw, user.Password)
+	if !ok || !needsRehash {
+		return ok
+	}
+	hash, err := hasher.Hash(raw)
+	if err != nil {
+		apps.Logger.Errorf("[Password] rehash error: %v", err)
+		return true
+	}
+	// 条件更新，避免覆盖同时修改的密码
+	if err := apps.DB.Model(&models.SysUser{}).
+		Where("id = ? AND password = ?", user.ID, user.Password).
+		Update("password", hash).Error; err != nil {
+		apps.Logger.Errorf("[Password] rehash error: %v", err)
+		return true
+	}
+	user.Password = hash
+	return true
+}
+
+// resetLink 拼接重置密码链接
+func resetLink(base, token string) string {
+	if base == "" {
+		return "token=" + token
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return base + "?token=" + token
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// newResetToken 生成找回密码 token
+func newResetToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
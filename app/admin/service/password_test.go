@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"wangzhiqiang/skeleton/app/admin/models"
+	"wangzhiqiang/skeleton/app/admin/types"
+	"wangzhiqiang/skeleton/app/tasks"
+	"wangzhiqiang/skeleton/pkg/app"
+	"wangzhiqiang/skeleton/pkg/cryptox"
+	"wangzhiqiang/skeleton/pkg/queue"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordService_ForgotKeepsTokenOutOfQueue(t *testing.T) {
+	ctx := newTestApps(t)
+	apps, err := app.GetApps(ctx)
+	require.NoError(t, err)
+	iq, err := queue.NewMemoryQueue(&queue.Config{})
+	require.NoError(t, err)
+	q := iq.(*queue.Memory)
+	apps.Queue = q
+	ctx = context.WithValue(ctx, app.ContextAppKey, apps)
+
+	hash, err := cryptox.HashMake("123456")
+	require.NoError(t, err)
+	user := models.SysUser{Email: "admin@example.com", Name: "admin", Password: hash}
+	require.NoError(t, apps.DB.Create(&user).Error)
+
+	s := new(PasswordService)
+	require.NoError(t, s.Forgot(ctx, &types.PasswordForgotReq{Email: user.Email}))
+
+	// 任务数据只有 ID
+	pushed := q.Pushed()
+	require.Len(t, pushed, 1)
+	data, err := json.Marshal(pushed[0])
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "token")
+	assert.NotContains(t, string(data), "http")
+
+	var body string
+	send := tasks.SendEmail
+	tasks.SendEmail = func(ctx context.Context, to, subject, b string) error {
+		body = b
+		return nil
+	}
+	t.Cleanup(func() { tasks.SendEmail = send })
+	require.NoError(t, pushed[0].Execute(ctx, q))
+
+	// 邮件中的链接可以重置密码
+	link, err := url.Parse(strings.TrimSpace(body[strings.Index(body, "http"):]))
+	require.NoError(t, err)
+	token := link.Query().Get("token")
+	require.NotEmpty(t, token)
+	require.NoError(t, s.Reset(ctx, &types.PasswordResetReq{Token: token, Password: "new-password1"}))
+	assert.ErrorIs(t, s.Reset(ctx, &types.PasswordResetReq{Token: token, Password: "new-password2"}), ErrInvalidResetToken)
+}
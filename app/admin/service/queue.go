@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"wangzhiqiang/skeleton/app/admin/types"
+	"wangzhiqiang/skeleton/pkg/app"
+	"wangzhiqiang/skeleton/pkg/database"
+	"wangzhiqiang/skeleton/pkg/queue"
+)
+
+// ErrQueueUnsupported 任务管理只支持 database 驱动
+var ErrQueueUnsupported = errors.New("当前队列驱动不支持任务管理，仅 database 驱动可用")
+
+type QueueService struct {
+}
+
+// gormQueue 获取数据库队列，其他驱动不支持任务管理
+func (s *QueueService) gormQueue(ctx context.Context) (*queue.Gorm, error) {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	q, ok := apps.Queue.(*queue.Gorm)
+	if !ok {
+		return nil, ErrQueueUnsupported
+	}
+	return q, nil
+}
+
+// List 分页获取待执行和执行中的任务
+func (s *QueueService) List(ctx context.Context, req *types.QueueListReq) (*database.PageResponse[queue.SysTask], error) {
+	q, err := s.gormQueue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	db := q.DB().Model(&queue.SysTask{})
+	if req.Queue != "" {
+		db = db.Where("queue = ?", req.Queue)
+	}
+	if req.Status != "" {
+		db = db.Where("status = ?", req.Status)
+	}
+	return database.Paginate[queue.SysTask](db.Order("id DESC"), req.PageRequest)
+}
+
+// View 查看任务
+func (s *QueueService) View(ctx context.Context, id uint) (*queue.SysTask, error) {
+	q, err := s.gormQueue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var task queue.SysTask
+	if err := q.DB().First(&task, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("任务不存在")
+		}
+		return nil, err
+	}
+	return &task, nil
+}
+
+// Delete 删除待执行的任务，执行中的任务无法删除
+func (s *QueueService) Delete(ctx context.Context, id uint) error {
+	q, err := s.gormQueue(ctx)
+	if err != nil {
+		return err
+	}
+	result := q.DB().Where("id = ? AND status = ?", id, queue.TaskStatusPending).Delete(&queue.SysTask{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("任务不存在或正在执行")
+	}
+	return nil
+}
+
+// Purge 清空队列中所有待执行的任务，返回删除的数量
+func (s *QueueService) Purge(ctx context.Context, req *types.QueuePurgeReq) (int64, error) {
+	q, err := s.gormQueue(ctx)
+	if err != nil {
+		return 0, err
+	}
+	result := q.DB().Where("queue = ? AND status = ?", req.Queue, queue.TaskStatusPending).Delete(&queue.SysTask{})
+	return result.RowsAffected, result.Error
+}
+
+// FailedList 分页获取死信任务
+func (s *QueueService) FailedList(ctx context.Context, req *types.QueueFailedListReq) (*database.PageResponse[queue.SysFailedTask], error) {
+	q, err := s.gormQueue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	db := q.DB().Model(&queue.SysFailedTask{})
+	if req.Queue != "" {
+		db = db.Where("queue = ?", req.Queue)
+	}
+	return database.Paginate[queue.SysFailedTask](db.Order("id DESC"), req.PageRequest)
+}
+
+// FailedView 查看死信任务
+func (s *QueueService) FailedView(ctx context.Context, id uint) (*queue.SysFailedTask, error) {
+	q, err := s.gormQueue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var task queue.SysFailedTask
+	if err := q.DB().First(&task, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("任务不存在")
+		}
+		return nil, err
+	}
+	return &task, nil
+}
+
+// FailedRetry 重试死信任务
+func (s *QueueService) FailedRetry(ctx context.Context, id uint) error {
+	q, err := s.gormQueue(ctx)
+	if err != nil {
+		return err
+	}
+	if err := q.RetryFailed(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("任务不存在")
+		}
+		return err
+	}
+	return nil
+}
+
+// FailedDelete 删除死信任务
+func (s *QueueService) FailedDelete(ctx context.Context, id uint) error {
+	q, err := s.gormQueue(ctx)
+	if err != nil {
+		return err
+	}
+	return q.DB().Delete(&queue.SysFailedTask{}, id).Error
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"wangzhiqiang/skeleton/app/admin/types"
+	"wangzhiqiang/skeleton/pkg/app"
+	"wangzhiqiang/skeleton/pkg/queue"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueService_UnsupportedDriver(t *testing.T) {
+	q, err := queue.NewMemoryQueue(&queue.Config{})
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), app.ContextAppKey, app.Apps{Queue: q})
+
+	s := new(QueueService)
+	_, err = s.List(ctx, &types.QueueListReq{})
+	assert.ErrorIs(t, err, ErrQueueUnsupported)
+	assert.ErrorIs(t, s.FailedRetry(ctx, 1), ErrQueueUnsupported)
+}
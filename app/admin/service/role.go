@@ -106,6 +106,25 @@ func (s *RoleService) Edit(ctx context.Context, req *types.RoleReq) error {
 	return casbinx.SyncRole(apps.Enforcer, &role)
 }
 
+// MFA 设置角色是否要求两步验证，只有超级管理员可以设置
+func (s *RoleService) MFA(ctx context.Context, req *types.RoleMFAReq, operator uint) error {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return err
+	}
+	if operator != apps.Config.System.SuperAdminUID {
+		return fmt.Errorf("只有超级管理员可以设置两步验证要求")
+	}
+	result := apps.DB.Model(&models.SysRole{}).Where("id = ?", req.ID).Update("require_mfa", req.RequireMFA)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("角色不存在")
+	}
+	return nil
+}
+
 // Delete 删除角色及菜单关联
 func (s *RoleService) Delete(ctx context.Context, id uint) error {
 	apps, err := app.GetApps(ctx)
@@ -1,8 +1,13 @@
 package service
 
 type Service struct {
-	Auth AuthService
-	Menu MenuService
-	Role RoleService
-	User UserService
+	APIKey   APIKeyService
+	Auth     AuthService
+	MFA      MFAService
+	Menu     MenuService
+	OAuth    OAuthService
+	Password PasswordService
+	Queue    QueueService
+	Role     RoleService
+	User     UserService
 }
@@ -6,6 +6,7 @@ import (
 	"gorm.io/gorm"
 	"wangzhiqiang/skeleton/app/admin/models"
 	"wangzhiqiang/skeleton/app/admin/types"
+	appModels "wangzhiqiang/skeleton/app/models"
 	"wangzhiqiang/skeleton/pkg/app"
 	"wangzhiqiang/skeleton/pkg/casbinx"
 	"wangzhiqiang/skeleton/pkg/database"
@@ -49,10 +50,15 @@ func (u UserService) Create(ctx context.Context, req *types.UserReq) error {
 		return err
 	}
 	user := models.SysUser{
-		Email:    req.Email,
-		Name:     req.Name,
-		Phone:    req.Phone,
-		Password: req.Password,
+		Email: req.Email,
+		Name:  req.Name,
+		Phone: req.Phone,
+	}
+	// 不设置密码的用户只能通过单点登录或找回密码登录
+	if req.Password != "" {
+		if user.Password, err = hashPassword(apps.Config.System.Password, req.Password); err != nil {
+			return err
+		}
 	}
 	var roles []*models.SysRole
 	if len(req.RoleIds) > 0 {
@@ -89,7 +95,12 @@ func (u UserService) Edit(ctx context.Context, req *types.UserReq) error {
 	updatedUser.Email = req.Email
 	updatedUser.Name = req.Name
 	updatedUser.Phone = req.Phone
-	updatedUser.Password = req.Password
+	// 密码为空时保持不变
+	if req.Password != "" {
+		if updatedUser.Password, err = hashPassword(apps.Config.System.Password, req.Password); err != nil {
+			return err
+		}
+	}
 	var roles []*models.SysRole
 	if len(req.RoleIds) > 0 {
 		if err := apps.DB.Where("id IN ?", req.RoleIds).Find(&roles).Error; err != nil {
@@ -116,6 +127,12 @@ func (u UserService) Edit(ctx context.Context, req *types.UserReq) error {
 			return err
 		}
 	}
+	// 管理员修改密码后注销该用户的所有会话
+	if req.Password != "" {
+		if _, err := apps.JWT.RevokeUserSessions(updatedUser.ID); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -149,10 +166,45 @@ func (u UserService) Delete(ctx context.Context, req *types.IDReq) error {
 		if err := casbinx.SyncUserRoles(apps.Enforcer, &user); err != nil {
 			return err
 		}
-		return nil
+		// 注销该用户的所有会话
+		_, err := apps.JWT.RevokeUserSessions(user.ID)
+		return err
 	})
 }
 
+// Unlock 解锁因登录失败次数过多被锁定的账号
+func (u UserService) Unlock(ctx context.Context, req *types.IDReq, operator uint, ip string) error {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return err
+	}
+	var user models.SysUser
+	if err := apps.DB.First(&user, req.ID).Error; err != nil {
+		return err
+	}
+	limiter := &loginLimiter{db: apps.DB, cfg: apps.Config.System.Login}
+	if err := limiter.reset(user.Email); err != nil {
+		return err
+	}
+	return apps.DB.Create(&appModels.SysAuditLog{
+		Action:     appModels.AuditUnlock,
+		UserID:     user.ID,
+		OperatorID: operator,
+		Ip:         ip,
+		Detail:     "email=" + user.Email,
+	}).Error
+}
+
+// Kick 踢下线，注销用户的所有会话
+func (u UserService) Kick(ctx context.Context, req *types.IDReq) error {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = apps.JWT.RevokeUserSessions(req.ID)
+	return err
+}
+
 // GetUserMenus 获取用户拥有的菜单（树形结构）
 func (u UserService) GetUserMenus(ctx context.Context, uid uint) ([]*models.SysMenu, error) {
 	apps, err := app.GetApps(ctx)
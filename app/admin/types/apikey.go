@@ -0,0 +1,14 @@
+package types
+
+import "wangzhiqiang/skeleton/app/admin/models"
+
+type APIKeyCreateReq struct {
+	Name      string   `json:"name" form:"name" binding:"required,max=50"`
+	Scopes    []string `json:"scopes" form:"scopes" binding:"required,min=1"` // 授权范围，例如 user:read、queue:*、*
+	ExpiresIn int      `json:"expires_in" form:"expires_in"`                  // 有效期（单位：天），0 表示不过期
+}
+
+type APIKeyCreateResp struct {
+	Key    string            `json:"key"` // 完整密钥，只在创建时返回一次
+	APIKey *models.SysAPIKey `json:"api_key"`
+}
@@ -3,9 +3,10 @@ package types
 import "wangzhiqiang/skeleton/app/admin/models"
 
 type LoginReq struct {
-	Email    string `json:"email" form:"email" param:"email" uri:"email" query:"email"`
-	Password string `json:"password" form:"password" param:"password" uri:"password" query:"password"`
-	IP       string
+	Email     string `json:"email" form:"email" param:"email" uri:"email" query:"email"`
+	Password  string `json:"password" form:"password" param:"password" uri:"password" query:"password"`
+	IP        string
+	UserAgent string
 }
 
 type RefreshTokenReq struct {
@@ -13,8 +14,12 @@ type RefreshTokenReq struct {
 }
 
 type LoginResp struct {
-	Token        string            `json:"token"`
-	RefreshToken string            `json:"refresh_token"`
-	ExpiresIn    int               `json:"expires_in"`
-	Menus        []*models.SysMenu `json:"menus"`
+	Token         string            `json:"token"`
+	RefreshToken  string            `json:"refresh_token"`
+	ExpiresIn     int               `json:"expires_in"`
+	Menus         []*models.SysMenu `json:"menus"`
+	MFARequired   bool              `json:"mfa_required,omitempty"`   // 需要两步验证，使用 mfa_token 提交验证码
+	MFAEnroll     bool              `json:"mfa_enroll,omitempty"`     // 角色要求两步验证但尚未启用，需要先绑定
+	MFAToken      string            `json:"mfa_token,omitempty"`      // 等待两步验证的 token
+	RecoveryCodes []string          `json:"recovery_codes,omitempty"` // 登录时完成绑定返回的恢复码
 }
@@ -0,0 +1,25 @@
+package types
+
+type MFALoginReq struct {
+	MFAToken  string `json:"mfa_token" form:"mfa_token" binding:"required"`
+	Code      string `json:"code" form:"code" binding:"required"` // TOTP 验证码或恢复码
+	IP        string
+	UserAgent string
+}
+
+type MFATokenReq struct {
+	MFAToken string `json:"mfa_token" form:"mfa_token" binding:"required"`
+}
+
+type MFACodeReq struct {
+	Code string `json:"code" form:"code" binding:"required"`
+}
+
+type MFASetupResp struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"` // otpauth URI，用于生成二维码
+}
+
+type MFAConfirmResp struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -0,0 +1,18 @@
+package types
+
+// OAuthState 发起单点登录时保存在会话中的参数，回调时校验
+type OAuthState struct {
+	State    string `json:"state"`
+	Verifier string `json:"verifier"` // PKCE code_verifier
+	Nonce    string `json:"nonce"`
+}
+
+type OAuthCallbackReq struct {
+	Code             string `json:"code" form:"code"`
+	State            string `json:"state" form:"state" binding:"required"`
+	Error            string `json:"error" form:"error"` // 身份提供方拒绝授权时返回
+	ErrorDescription string `json:"error_description" form:"error_description"`
+	Provider         string
+	IP               string
+	UserAgent        string
+}
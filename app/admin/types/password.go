@@ -0,0 +1,19 @@
+package types
+
+type PasswordChangeReq struct {
+	OldPassword string `json:"old_password" form:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" form:"new_password" binding:"required"`
+	IP          string
+	UserAgent   string
+}
+
+type PasswordForgotReq struct {
+	Email string `json:"email" form:"email" binding:"required,email"`
+	IP    string
+}
+
+type PasswordResetReq struct {
+	Token    string `json:"token" form:"token" binding:"required"`
+	Password string `json:"password" form:"password" binding:"required"`
+	IP       string
+}
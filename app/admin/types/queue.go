@@ -0,0 +1,18 @@
+package types
+
+import "wangzhiqiang/skeleton/pkg/database"
+
+type QueueListReq struct {
+	database.PageRequest
+	Queue  string `json:"queue" form:"queue" param:"queue" uri:"queue" query:"queue"`
+	Status string `json:"status" form:"status" param:"status" uri:"status" query:"status" binding:"omitempty,oneof=pending reserved done"`
+}
+
+type QueueFailedListReq struct {
+	database.PageRequest
+	Queue string `json:"queue" form:"queue" param:"queue" uri:"queue" query:"queue"`
+}
+
+type QueuePurgeReq struct {
+	Queue string `json:"queue" form:"queue" param:"queue" uri:"queue" query:"queue" binding:"required"`
+}
@@ -18,3 +18,8 @@ type RoleAuthReq struct {
 	RoleID  uint   `json:"role_id" form:"role_id"`
 	MenuIDs []uint `json:"menu_ids" form:"menu_ids"`
 }
+
+type RoleMFAReq struct {
+	ID         uint `json:"id" form:"id" binding:"required"`
+	RequireMFA bool `json:"require_mfa" form:"require_mfa"`
+}
@@ -0,0 +1,24 @@
+package apis
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"wangzhiqiang/skeleton/pkg/app"
+)
+
+type JWKS struct {
+}
+
+func (j *JWKS) Routes(ctx context.Context, g *gin.Engine) error {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return err
+	}
+	// 公开签名公钥，供其他服务校验 token
+	g.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, apps.JWT.JWKS())
+	})
+	return nil
+}
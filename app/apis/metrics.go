@@ -0,0 +1,30 @@
+package apis
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"wangzhiqiang/skeleton/pkg/app"
+	"wangzhiqiang/skeleton/pkg/httpx"
+	"wangzhiqiang/skeleton/pkg/queue"
+)
+
+type Metrics struct {
+}
+
+// Routes 配置开启后注册 /metrics，配置 token 时采集需要携带 Bearer token
+func (m *Metrics) Routes(ctx context.Context, g *gin.Engine) error {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return err
+	}
+	cfg := apps.Config.Metrics
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+	// 队列深度在采集时从存储中统计
+	if err := queue.RegisterDepthCollector(apps.Queue); err != nil {
+		return err
+	}
+	g.GET("/metrics", gin.WrapH(httpx.MetricsHandler(cfg.Token)))
+	return nil
+}
@@ -3,9 +3,12 @@ package apis
 import (
 	"context"
 	"github.com/gin-gonic/gin"
+	"time"
 	"wangzhiqiang/skeleton/app/tasks"
 	"wangzhiqiang/skeleton/pkg/app"
 	"wangzhiqiang/skeleton/pkg/httpx"
+	"wangzhiqiang/skeleton/pkg/httpx/mws"
+	"wangzhiqiang/skeleton/pkg/queue"
 )
 
 type Task struct {
@@ -18,11 +21,11 @@ func (c *Task) Routes(ctx context.Context, g *gin.Engine) error {
 			httpx.ApiError(context, err)
 			return
 		}
-		if err := apps.Queue.Push(&tasks.EmailTask{
+		if _, err := apps.Queue.Push(&tasks.EmailTask{
 			To:      "test@example.com",
 			Subject: "测试邮件",
 			Body:    "这是邮件内容",
-		}, 0); err != nil {
+		}, 0, queue.WithQueue("mail"), queue.WithUnique(time.Minute), queue.WithRequestID(mws.GetRequestID(context))); err != nil {
 			httpx.ApiError(context, err)
 			return
 		}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"time"
 	"wangzhiqiang/skeleton/app/admin/middlewares"
@@ -15,7 +16,40 @@ import (
 	"wangzhiqiang/skeleton/pkg/httpx/mws"
 )
 
-func AccessLog(db *gorm.DB) gin.HandlerFunc {
+const (
+	ctxKeyOmitRequest  = "access_log_omit_request"
+	ctxKeyOmitResponse = "access_log_omit_response"
+
+	omitted = "[敏感内容不记录]"
+)
+
+// sensitiveQuery 不记录值的查询参数，token 和授权码可以直接用于登录
+var sensitiveQuery = []string{"token", "access_token", "refresh_token", "code"}
+
+// OmitRequest 不记录请求内容，用于提交密码等敏感信息的接口
+func OmitRequest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(ctxKeyOmitRequest, true)
+		c.Next()
+	}
+}
+
+// OmitResponse 不记录响应内容，用于返回密钥、恢复码等敏感信息的接口
+func OmitResponse() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(ctxKeyOmitResponse, true)
+		c.Next()
+	}
+}
+
+// AccessLog 记录访问日志，redact 为额外不记录值的查询参数，如配置的 token 查询参数和 cookie 名称
+func AccessLog(db *gorm.DB, redact ...string) gin.HandlerFunc {
+	redacted := make(map[string]bool)
+	for _, name := range slices.Concat(sensitiveQuery, redact) {
+		if name != "" {
+			redacted[strings.ToLower(name)] = true
+		}
+	}
 	return func(c *gin.Context) {
 		var (
 			body   []byte
@@ -25,16 +59,7 @@ func AccessLog(db *gorm.DB) gin.HandlerFunc {
 		// 捕获请求 body
 		switch c.Request.Method {
 		case http.MethodGet:
-			query, _ := url.QueryUnescape(c.Request.URL.RawQuery)
-			split := strings.Split(query, "&")
-			m := make(map[string]string)
-			for _, v := range split {
-				kv := strings.SplitN(v, "=", 2)
-				if len(kv) == 2 {
-					m[kv[0]] = kv[1]
-				}
-			}
-			body, _ = json.Marshal(&m)
+			body = queryBody(c.Request.URL.RawQuery, redacted)
 		default:
 			body, err = io.ReadAll(c.Request.Body)
 			if err == nil {
@@ -66,8 +91,13 @@ func AccessLog(db *gorm.DB) gin.HandlerFunc {
 			Latency:   time.Since(start).Milliseconds(),
 			Response:  respBody.String(),
 		}
+		if c.GetBool(ctxKeyOmitResponse) {
+			record.Response = omitted
+		}
 		// 处理请求内容
-		if strings.Contains(c.GetHeader("Content-Type"), "multipart/form-data") {
+		if c.GetBool(ctxKeyOmitRequest) {
+			record.Request = omitted
+		} else if strings.Contains(c.GetHeader("Content-Type"), "multipart/form-data") {
 			record.Request = "multipart/form-data"
 		} else {
 			if len(body) > 1024 {
@@ -83,6 +113,24 @@ func AccessLog(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
+// queryBody 将查询参数转成 JSON 记录，redacted 中的参数只记录占位符
+func queryBody(rawQuery string, redacted map[string]bool) []byte {
+	query, _ := url.QueryUnescape(rawQuery)
+	split := strings.Split(query, "&")
+	m := make(map[string]string)
+	for _, v := range split {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) == 2 {
+			if redacted[strings.ToLower(kv[0])] {
+				kv[1] = omitted
+			}
+			m[kv[0]] = kv[1]
+		}
+	}
+	body, _ := json.Marshal(&m)
+	return body
+}
+
 // bodyWriter 用于捕获响应 body
 type bodyWriter struct {
 	gin.ResponseWriter
@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryBody_Redact(t *testing.T) {
+	redacted := map[string]bool{"token": true, "code": true, "ws_token": true}
+	var got map[string]string
+	require.NoError(t, json.Unmarshal(queryBody("page=1&TOKEN=abc&code=xyz&ws_token=t%3D1&name=a%20b", redacted), &got))
+	assert.Equal(t, map[string]string{
+		"page":     "1",
+		"TOKEN":    omitted,
+		"code":     omitted,
+		"ws_token": omitted,
+		"name":     "a b",
+	}, got)
+}
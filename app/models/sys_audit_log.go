@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	AuditLoginLocked    = "login_locked"    // 登录失败次数过多，账号被锁定
+	AuditIPBlocked      = "ip_blocked"      // 登录失败次数过多，IP 被封禁
+	AuditUnlock         = "unlock"          // 管理员解锁账号
+	AuditMFAEnabled     = "mfa_enabled"     // 启用两步验证
+	AuditMFADisabled    = "mfa_disabled"    // 关闭两步验证
+	AuditMFARecovery    = "mfa_recovery"    // 使用恢复码完成两步验证
+	AuditOAuthCreate    = "oauth_create"    // 单点登录自动创建用户
+	AuditOAuthLink      = "oauth_link"      // 单点登录按邮箱关联已有用户
+	AuditOAuthRoles     = "oauth_roles"     // 单点登录按用户组同步角色
+	AuditAPIKeyCreate   = "api_key_create"  // 创建 API Key
+	AuditAPIKeyRevoke   = "api_key_revoke"  // 吊销 API Key
+	AuditPasswordChange = "password_change" // 修改密码
+	AuditPasswordReset  = "password_reset"  // 通过找回密码重置密码
+)
+
+// SysAuditLog 安全审计日志
+type SysAuditLog struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement;comment:主键ID" json:"id"`
+	Action     string    `gorm:"index;type:varchar(50);comment:事件类型" json:"action"`
+	UserID     uint      `gorm:"index;type:bigint;comment:相关用户ID" json:"user_id"`
+	OperatorID uint      `gorm:"type:bigint;comment:操作人ID，0 表示系统" json:"operator_id"`
+	Ip         string    `gorm:"type:varchar(45);comment:请求IP" json:"ip"`
+	Detail     string    `gorm:"type:varchar(500);comment:详情" json:"detail"`
+	CreatedAt  time.Time `gorm:"autoCreateTime;index;comment:创建时间" json:"created_at"`
+}
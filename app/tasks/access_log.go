@@ -0,0 +1,32 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"wangzhiqiang/skeleton/app/models"
+	"wangzhiqiang/skeleton/pkg/app"
+	"wangzhiqiang/skeleton/pkg/queue"
+)
+
+// PruneAccessLogTask 清理过期的访问日志
+type PruneAccessLogTask struct {
+	Days int `json:"days"` // 保留天数
+}
+
+func (p *PruneAccessLogTask) Execute(ctx context.Context, q queue.IQueue) error {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return err
+	}
+	if p.Days <= 0 {
+		return fmt.Errorf("invalid retention days: %d", p.Days)
+	}
+	before := time.Now().AddDate(0, 0, -p.Days)
+	result := apps.DB.Where("created_at < ?", before).Delete(&models.SysAccessLog{})
+	if result.Error != nil {
+		return result.Error
+	}
+	apps.Logger.Infof("[PruneAccessLogTask] deleted %d access logs before %s", result.RowsAffected, before.Format(time.DateTime))
+	return nil
+}
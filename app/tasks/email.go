@@ -2,42 +2,39 @@ package tasks
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"time"
 	"wangzhiqiang/skeleton/pkg/app"
 	"wangzhiqiang/skeleton/pkg/queue"
 )
 
 type EmailTask struct {
-	To       string `json:"to"`
-	Body     string `json:"body"`
-	Subject  string `json:"subject"`
-	Attempts int    `json:"attempts"` // ✅ 建议改为 int（见下）
+	To      string `json:"to"`
+	Body    string `json:"body"`
+	Subject string `json:"subject"`
 }
 
-func (e *EmailTask) Execute(ctx context.Context, queue queue.IQueue) error {
-	apps, err := app.GetApps(ctx)
-	if err != nil {
-		confJSON, err := json.MarshalIndent(apps.Config, "", "  ")
-		if err != nil {
-			fmt.Println("[EmailTask] ❌ 配置序列化失败:", err)
-		} else {
-			fmt.Printf("[EmailTask] ✅ 当前配置:\n%s\n", confJSON)
-		}
+// RetryPolicy 邮件发送失败最多尝试 5 次
+func (e *EmailTask) RetryPolicy() queue.RetryPolicy {
+	return queue.RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     5 * time.Second,
+		MaxBackoff:  5 * time.Minute,
 	}
+}
 
-	// 日志格式优化
-	fmt.Printf("[EmailTask] 📧 第 %d 次尝试发送邮件\n\t👉 收件人: %s\n\t👉 标题: %s\n\t👉 内容: %s\n", e.Attempts, e.To, e.Subject, e.Body)
+func (e *EmailTask) Execute(ctx context.Context, q queue.IQueue) error {
+	return SendEmail(ctx, e.To, e.Subject, e.Body)
+}
 
+// SendEmail 发送邮件，返回 error 即可由队列按 RetryPolicy 自动重试，可在测试中替换
+//
+//	邮件内容可能包含重置密码链接等凭证，不写入日志
+var SendEmail = func(ctx context.Context, to, subject, body string) error {
+	apps, err := app.GetApps(ctx)
+	if err != nil {
+		return err
+	}
 	// TODO: 发送邮件逻辑
-
-	// 示例：失败重试
-	/*
-		attempts, _ := strconv.Atoi(e.Attempts)
-		if sendFailed {
-			e.Attempts = strconv.Itoa(attempts + 1)
-			_ = queue.Push(e, time.Second*5)
-		}
-	*/
+	apps.Logger.Infof("[EmailTask] send mail to: %s subject: %s attempts: %d", to, subject, queue.GetAttempts(ctx))
 	return nil
 }
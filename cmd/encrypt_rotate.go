@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"wangzhiqiang/skeleton/pkg/cryptox"
+	"wangzhiqiang/skeleton/pkg/database"
+
+	"github.com/urfave/cli/v3"
+)
+
+const (
+	FlagRotateBatch = "batch" // 每批处理的行数
+)
+
+// EncryptRotateCommand 返回一个使用当前密钥重新加密所有加密字段的 CLI 命令
+func EncryptRotateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "encrypt:rotate",
+		Usage: "Re-encrypt all encrypted columns with the active encryption key",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  FlagRotateBatch,
+				Value: 500,
+				Usage: "Number of rows to process per batch",
+			},
+		},
+		Action: func(ctx context.Context, command *cli.Command) error {
+			keyring, err := cryptox.NewKeyring(cfg.Encryption)
+			if err != nil {
+				return err
+			}
+			database.SetKeyring(keyring)
+			db, err := database.Init(cfg.Database)
+			if err != nil {
+				return err
+			}
+			n, err := database.Reencrypt(db, command.Int(FlagRotateBatch))
+			fmt.Printf("re-encrypted %d rows with key %s\n", n, keyring.ActiveVersion())
+			return err
+		},
+	}
+}
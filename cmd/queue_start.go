@@ -3,17 +3,83 @@ package cmd
 import (
 	"context"
 	"wangzhiqiang/skeleton/bootstrap"
+	"wangzhiqiang/skeleton/pkg/httpx"
 
 	"github.com/urfave/cli/v3"
 )
 
+const (
+	FlagQueueWorkers         = "workers"           // 并发 worker 数
+	FlagQueueBatchSize       = "batch-size"        // 每次拉取任务数
+	FlagQueuePollInterval    = "poll-interval"     // 最短拉取间隔
+	FlagQueueMaxPollInterval = "max-poll-interval" // 空闲时最长拉取间隔
+	FlagQueueQueues          = "queues"            // 要消费的队列
+	FlagQueueStrict          = "strict"            // 严格按队列顺序消费
+	FlagQueueMetricsListen   = "metrics-listen"    // 指标监听地址
+)
+
 // QueueStartCommand 返回一个用于启动队列处理器的 CLI 命令
 func QueueStartCommand() *cli.Command {
 	return &cli.Command{
 		Name:  "queue:start",
 		Usage: "Start the queue worker to consume and execute pending tasks",
-		Flags: []cli.Flag{},
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  FlagQueueWorkers,
+				Usage: "Maximum number of tasks executed concurrently (overrides queue.workers)",
+			},
+			&cli.IntFlag{
+				Name:  FlagQueueBatchSize,
+				Usage: "Maximum number of tasks fetched per poll (overrides queue.batch_size)",
+			},
+			&cli.DurationFlag{
+				Name:  FlagQueuePollInterval,
+				Usage: "Minimum poll interval when the queue is idle, e.g. 500ms (overrides queue.poll_interval)",
+			},
+			&cli.DurationFlag{
+				Name:  FlagQueueMaxPollInterval,
+				Usage: "Maximum poll interval the idle backoff grows to, e.g. 5s (overrides queue.max_poll_interval)",
+			},
+			&cli.StringSliceFlag{
+				Name:  FlagQueueQueues,
+				Usage: "Queues to consume, as name or name:weight, e.g. mail:3,default (overrides queue.queues)",
+			},
+			&cli.BoolFlag{
+				Name:  FlagQueueStrict,
+				Usage: "Consume queues in the listed order instead of by weight (overrides queue.strict)",
+			},
+			&cli.StringFlag{
+				Name:  FlagQueueMetricsListen,
+				Usage: "Expose worker metrics on this address, e.g. 127.0.0.1:9091 (overrides metrics.listen and enables metrics)",
+			},
+		},
 		Action: func(ctx context.Context, command *cli.Command) error {
+			// 命令行参数覆盖配置文件
+			if command.IsSet(FlagQueueWorkers) {
+				cfg.Queue.Workers = int(command.Int(FlagQueueWorkers))
+			}
+			if command.IsSet(FlagQueueBatchSize) {
+				cfg.Queue.BatchSize = int(command.Int(FlagQueueBatchSize))
+			}
+			if command.IsSet(FlagQueuePollInterval) {
+				cfg.Queue.PollInterval = int(command.Duration(FlagQueuePollInterval).Milliseconds())
+			}
+			if command.IsSet(FlagQueueMaxPollInterval) {
+				cfg.Queue.MaxPollInterval = int(command.Duration(FlagQueueMaxPollInterval).Milliseconds())
+			}
+			if command.IsSet(FlagQueueQueues) {
+				cfg.Queue.Queues = command.StringSlice(FlagQueueQueues)
+			}
+			if command.IsSet(FlagQueueStrict) {
+				cfg.Queue.Strict = command.Bool(FlagQueueStrict)
+			}
+			if command.IsSet(FlagQueueMetricsListen) {
+				if cfg.Metrics == nil {
+					cfg.Metrics = &httpx.MetricsConfig{}
+				}
+				cfg.Metrics.Enabled = true
+				cfg.Metrics.Listen = command.String(FlagQueueMetricsListen)
+			}
 			bootstrap.App(cfg).StartQueue()
 			return nil
 		},
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"wangzhiqiang/skeleton/bootstrap"
+	"wangzhiqiang/skeleton/pkg/queue"
+
+	"github.com/urfave/cli/v3"
+)
+
+// ScheduleRunCommand 返回一个用于启动周期任务调度器的 CLI 命令
+func ScheduleRunCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "schedule:run",
+		Usage: "Start the scheduler to push registered cron tasks into the queue",
+		Flags: []cli.Flag{},
+		Action: func(ctx context.Context, command *cli.Command) error {
+			// 内存队列只能在推送任务的进程内消费，调度器随 http 进程启动
+			if cfg.Queue != nil && cfg.Queue.Driver == queue.DriverMemory {
+				return errors.New("schedule:run does not support the memory queue driver, the scheduler runs inside the http process instead")
+			}
+			bootstrap.App(cfg).StartSchedule()
+			return nil
+		},
+	}
+}
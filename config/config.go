@@ -4,27 +4,101 @@ package config
 import (
 	"gopkg.in/yaml.v3"
 	"os"
+	"time"
+	"wangzhiqiang/skeleton/pkg/cryptox"
 	"wangzhiqiang/skeleton/pkg/database"
 	"wangzhiqiang/skeleton/pkg/httpx"
 	"wangzhiqiang/skeleton/pkg/httpx/mws"
 	"wangzhiqiang/skeleton/pkg/jwts"
 	"wangzhiqiang/skeleton/pkg/logger"
+	"wangzhiqiang/skeleton/pkg/oidcx"
+	"wangzhiqiang/skeleton/pkg/password"
 	"wangzhiqiang/skeleton/pkg/queue"
+	"wangzhiqiang/skeleton/pkg/redisx"
 )
 
 // Config 应用配置结构体
 // 包含服务器、数据库和日志记录器的配置
 type Config struct {
-	Server   *httpx.Config    `yaml:"server" json:"server,omitempty"`     // 服务器配置，包括监听端口、运行模式、超时设置及会话配置
-	Database *database.Config `yaml:"database" json:"database,omitempty"` // 数据库配置，包括驱动类型、连接信息、连接池参数等
-	Logger   *logger.Config   `yaml:"logger" json:"logger,omitempty"`     // 日志记录器配置，包括日志级别、文件路径、格式、切割与压缩策略
-	Queue    *queue.Config    `yaml:"queue" json:"queue,omitempty"`       // 队列配置
-	JWT      *jwts.Config     `yaml:"jwt" json:"jwt,omitempty"`           // JWT 配置，包括密钥、过期时间、签发者和受众信息
-	System   *SystemConfig    `yaml:"system" json:"system,omitempty"`     // 系统配置，包括超级管理员 ID 等全局系统参数
+	Server     *httpx.Config             `yaml:"server" json:"server,omitempty"`         // 服务器配置，包括监听端口、运行模式、超时设置及会话配置
+	Database   *database.Config          `yaml:"database" json:"database,omitempty"`     // 数据库配置，包括驱动类型、连接信息、连接池参数等
+	Redis      *redisx.Config            `yaml:"redis" json:"redis,omitempty"`           // Redis 配置，包括地址、密码和数据库编号
+	Logger     *logger.Config            `yaml:"logger" json:"logger,omitempty"`         // 日志记录器配置，包括日志级别、文件路径、格式、切割与压缩策略
+	Queue      *queue.Config             `yaml:"queue" json:"queue,omitempty"`           // 队列配置
+	Metrics    *httpx.MetricsConfig      `yaml:"metrics" json:"metrics,omitempty"`       // Prometheus 指标配置，默认不暴露
+	JWT        *jwts.Config              `yaml:"jwt" json:"jwt,omitempty"`               // JWT 配置，包括密钥、过期时间、签发者和受众信息
+	OAuth      map[string]*oidcx.Config  `yaml:"oauth" json:"oauth,omitempty"`           // OIDC 单点登录身份提供方，key 为登录地址中的 provider
+	Encryption *cryptox.EncryptionConfig `yaml:"encryption" json:"encryption,omitempty"` // 字段加密密钥，存在 serializer:encrypted 字段时必须配置
+	System     *SystemConfig             `yaml:"system" json:"system,omitempty"`         // 系统配置，包括超级管理员 ID 等全局系统参数
 }
 
 type SystemConfig struct {
-	SuperAdminUID uint `yaml:"super_admin_uid" json:"super_admin_uid,omitempty"`
+	SuperAdminUID uint            `yaml:"super_admin_uid" json:"super_admin_uid,omitempty"`
+	Login         *LoginConfig    `yaml:"login" json:"login,omitempty"`       // 登录防暴力破解配置
+	Password      *PasswordConfig `yaml:"password" json:"password,omitempty"` // 密码策略和找回密码配置
+	// 各路由分组的 token 来源，key 为分组名称，未配置的分组只从 Authorization 请求头读取 Bearer token
+	Token map[string]*TokenSourceConfig `yaml:"token" json:"token,omitempty"`
+}
+
+// TokenSource 返回路由分组的 token 来源，未配置时返回空配置
+func (c *SystemConfig) TokenSource(group string) *TokenSourceConfig {
+	if src, ok := c.Token[group]; ok && src != nil {
+		return src
+	}
+	return &TokenSourceConfig{}
+}
+
+// TokenSourceConfig 请求中 token 的来源，依次尝试请求头、cookie、查询参数，未配置的来源不读取
+type TokenSourceConfig struct {
+	Header string `yaml:"header" json:"header,omitempty"` // 请求头名称，只接受 Bearer 方案，默认 Authorization
+	Cookie string `yaml:"cookie" json:"cookie,omitempty"` // cookie 名称
+	Query  string `yaml:"query" json:"query,omitempty"`   // 查询参数名称，用于无法设置请求头的 WebSocket、SSE 握手
+}
+
+// LoginConfig 登录失败限制，按账号和 IP 分别统计窗口内的失败次数
+type LoginConfig struct {
+	MaxAttempts   int `yaml:"max_attempts" json:"max_attempts,omitempty"`       // 窗口内账号最大失败次数，达到后锁定账号，默认 5
+	IPMaxAttempts int `yaml:"ip_max_attempts" json:"ip_max_attempts,omitempty"` // 窗口内单个 IP 最大失败次数，达到后封禁 IP，默认 20
+	Window        int `yaml:"window" json:"window,omitempty"`                   // 统计窗口（秒），默认 900
+	LockDuration  int `yaml:"lock_duration" json:"lock_duration,omitempty"`     // 锁定时长（秒），默认 1800
+	Delay         int `yaml:"delay" json:"delay,omitempty"`                     // 失败后再次尝试的初始间隔（毫秒），每次失败翻倍，默认 1000
+	MaxDelay      int `yaml:"max_delay" json:"max_delay,omitempty"`             // 再次尝试的最大间隔（毫秒），默认 30000
+}
+
+// GetWindow 返回失败次数统计窗口
+func (c *LoginConfig) GetWindow() time.Duration {
+	return time.Duration(c.Window) * time.Second
+}
+
+// GetLockDuration 返回锁定时长
+func (c *LoginConfig) GetLockDuration() time.Duration {
+	return time.Duration(c.LockDuration) * time.Second
+}
+
+// GetDelay 返回第 failures 次失败后需要等待的时间
+func (c *LoginConfig) GetDelay(failures int) time.Duration {
+	if failures <= 0 || c.Delay <= 0 {
+		return 0
+	}
+	delay := time.Duration(c.Delay) * time.Millisecond
+	maxDelay := time.Duration(c.MaxDelay) * time.Millisecond
+	for i := 1; i < failures && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+// PasswordConfig 密码策略、哈希和找回密码配置
+type PasswordConfig struct {
+	password.Policy `yaml:",inline"`
+	Hash            cryptox.HashConfig `yaml:"hash" json:"hash,omitempty"`                         // 哈希算法和参数，修改后用户下次登录时自动重新哈希
+	ResetExpiration int                `yaml:"reset_expiration" json:"reset_expiration,omitempty"` // 重置密码链接有效期（秒），默认 1800
+	ResetURL        string             `yaml:"reset_url" json:"reset_url,omitempty"`               // 前端重置密码页面地址，token 作为查询参数追加
+}
+
+// GetResetExpiration 返回重置密码链接有效期
+func (c *PasswordConfig) GetResetExpiration() time.Duration {
+	return time.Duration(c.ResetExpiration) * time.Second
 }
 
 var (
@@ -49,6 +123,14 @@ var (
 	defaultSystem = &SystemConfig{
 		SuperAdminUID: 1,
 	}
+	defaultLogin = LoginConfig{
+		MaxAttempts:   5,
+		IPMaxAttempts: 20,
+		Window:        900,
+		LockDuration:  1800,
+		Delay:         1000,
+		MaxDelay:      30000,
+	}
 	defaultJWT = &jwts.Config{
 		Secret: "vosMykI4axI9IrUuI8JYxlaHnnEWLvfNrWE3gOwOBBk=",
 	}
@@ -68,6 +150,16 @@ func Load(path string) (*Config, error) {
 	if cfg.System == nil {
 		cfg.System = defaultSystem
 	}
+	if cfg.System.Login == nil {
+		cfg.System.Login = &LoginConfig{}
+	}
+	setLoginDefaults(cfg.System.Login)
+	if cfg.System.Password == nil {
+		cfg.System.Password = &PasswordConfig{}
+	}
+	if cfg.System.Password.ResetExpiration <= 0 {
+		cfg.System.Password.ResetExpiration = 1800
+	}
 	if cfg.Server.Session == nil {
 		cfg.Server.Session = defaultServerSession
 	}
@@ -86,3 +178,25 @@ func Load(path string) (*Config, error) {
 	}
 	return cfg, nil
 }
+
+// setLoginDefaults 未配置的登录限制项使用默认值
+func setLoginDefaults(c *LoginConfig) {
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaultLogin.MaxAttempts
+	}
+	if c.IPMaxAttempts <= 0 {
+		c.IPMaxAttempts = defaultLogin.IPMaxAttempts
+	}
+	if c.Window <= 0 {
+		c.Window = defaultLogin.Window
+	}
+	if c.LockDuration <= 0 {
+		c.LockDuration = defaultLogin.LockDuration
+	}
+	if c.Delay <= 0 {
+		c.Delay = defaultLogin.Delay
+	}
+	if c.MaxDelay < c.Delay {
+		c.MaxDelay = max(defaultLogin.MaxDelay, c.Delay)
+	}
+}
@@ -23,6 +23,9 @@ func init() {
 	// 添加 HTTP 命令到命令列表
 	commands = append(commands, cmd.HTTPCommand())
 	commands = append(commands, cmd.QueueStartCommand())
+	commands = append(commands, cmd.ScheduleRunCommand())
+	commands = append(commands, cmd.JWTKeygenCommand())
+	commands = append(commands, cmd.EncryptRotateCommand())
 }
 
 // 主函数
@@ -1,27 +1,33 @@
 package app
 
 import (
+	"log"
 	"wangzhiqiang/skeleton/config"
+	"wangzhiqiang/skeleton/pkg/queue"
 
 	"go.uber.org/fx"
 )
 
 type App struct {
-	opts []fx.Option
+	config *config.Config
+	opts   []fx.Option
 }
 
 // New 创建一个新的 App 实例
 func New(cfg *config.Config) *App {
-	app := &App{opts: make([]fx.Option, 0)}
+	app := &App{config: cfg, opts: make([]fx.Option, 0)}
 
 	app.AddProvide(
 		func() *config.Config { return cfg },
 		ProvideLogger,     // 提供日志记录器
+		ProvideKeyring,    // 提供字段加密密钥环
 		ProvideDatabase,   // 提供数据库
+		ProvideRedis,      // 提供Redis
 		ProvideEnforcer,   // 提供Casbin
 		ProvideHTTPServer, // 提供服务器
 		ProvideQueue,      // 提供队列
 		ProvideJWT,        // 提供JWT服务
+		ProvideOAuth,      // 提供OIDC单点登录
 	)
 	//if cfg.Server.Mode != "debug" {
 	app.AddOpts(fx.NopLogger)
@@ -56,15 +62,36 @@ func (a *App) FX() *fx.App {
 }
 
 // StartHTTP 启动 HTTP 服务器
+//
+//	内存队列无法跨进程消费，使用 memory 驱动时在同一进程内启动队列处理器和周期任务调度器
 func (a *App) StartHTTP() {
 	a.AddInvoke(NewInvokeHTTP)
-	app := a.FX()
-	app.Run()
+	if a.config.Queue != nil && a.config.Queue.Driver == queue.DriverMemory {
+		a.AddInvoke(NewInvokeQueue)
+		a.AddInvoke(NewInvokeSchedule)
+	}
+	run(a.FX())
 }
 
 // StartQueue 启动队列处理器
+//
+//	任务执行的指标只在 worker 进程中统计，配置 metrics.listen 时通过独立端口暴露
 func (a *App) StartQueue() {
 	a.AddInvoke(NewInvokeQueue)
-	app := a.FX()
+	a.AddInvoke(NewInvokeMetrics)
+	run(a.FX())
+}
+
+// StartSchedule 启动周期任务调度器
+func (a *App) StartSchedule() {
+	a.AddInvoke(NewInvokeSchedule)
+	run(a.FX())
+}
+
+// run 运行 fx 应用，fx 日志已关闭，依赖构造失败（如缺少必填密钥）时需要输出错误后退出
+func run(app *fx.App) {
+	if err := app.Err(); err != nil {
+		log.Fatal(err)
+	}
 	app.Run()
 }
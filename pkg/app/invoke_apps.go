@@ -4,12 +4,15 @@ import (
 	"context"
 	"fmt"
 	"github.com/casbin/casbin/v2"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/fx"
 	"gorm.io/gorm"
 	"sync"
 	"wangzhiqiang/skeleton/config"
+	"wangzhiqiang/skeleton/pkg/cryptox"
 	"wangzhiqiang/skeleton/pkg/jwts"
 	"wangzhiqiang/skeleton/pkg/logger"
+	"wangzhiqiang/skeleton/pkg/oidcx"
 	"wangzhiqiang/skeleton/pkg/queue"
 )
 
@@ -43,8 +46,11 @@ type Apps struct {
 	Config   *config.Config
 	Queue    queue.IQueue
 	DB       *gorm.DB
+	Redis    *redis.Client
 	JWT      *jwts.JWT
 	Enforcer *casbin.Enforcer
+	Keyring  *cryptox.Keyring
+	OAuth    *oidcx.Providers
 }
 
 func GetApps(ctx context.Context) (Apps, error) {
@@ -54,6 +60,13 @@ func GetApps(ctx context.Context) (Apps, error) {
 	return Apps{}, fmt.Errorf("apps not found in context")
 }
 
+// WithApps 将全局依赖写入 ctx，用于需要随请求取消的调用，例如访问外部服务
+func WithApps(ctx context.Context) context.Context {
+	ctxAppLock.Lock()
+	defer ctxAppLock.Unlock()
+	return context.WithValue(ctx, ContextAppKey, ctxApp)
+}
+
 func NewApps(app Apps) {
 	app.Lc.Append(fx.Hook{
 		// OnStop 钩子：在应用停止时执行
@@ -65,7 +78,8 @@ func NewApps(app Apps) {
 			// 保存到全局变量
 			ctxAppLock.Lock()
 			ctxApp = app
-			appContext = context.WithValue(ctx, ContextAppKey, ctxApp)
+			// OnStart 的 ctx 带有启动超时，启动完成后即被取消，全局 context 只保留值
+			appContext = context.WithValue(context.WithoutCancel(ctx), ContextAppKey, ctxApp)
 			for _, initApp := range _initApps {
 				initApp.Init(appContext)
 			}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"wangzhiqiang/skeleton/config"
+	"wangzhiqiang/skeleton/pkg/httpx"
+	"wangzhiqiang/skeleton/pkg/logger"
+	"wangzhiqiang/skeleton/pkg/queue"
+
+	"go.uber.org/fx"
+)
+
+type InvokeMetrics struct {
+	fx.In
+	Lc     fx.Lifecycle
+	Logger logger.ILogger
+	Config *config.Config
+	Queue  queue.IQueue
+}
+
+// NewInvokeMetrics 在独立端口上暴露指标，用于没有 HTTP 服务的进程，未开启或未配置 listen 时不启动
+func NewInvokeMetrics(app InvokeMetrics) {
+	cfg := app.Config.Metrics
+	if cfg == nil || !cfg.Enabled || cfg.Listen == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", httpx.MetricsHandler(cfg.Token))
+	srv := &http.Server{Addr: cfg.Listen, Handler: mux}
+	app.Lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			if err := queue.RegisterDepthCollector(app.Queue); err != nil {
+				return err
+			}
+			// 先监听再返回，端口被占用时启动失败
+			ln, err := net.Listen("tcp", cfg.Listen)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					app.Logger.Errorf("[InvokeMetrics] serve error: %v", err)
+				}
+			}()
+			app.Logger.Infof("[InvokeMetrics] metrics listening on %s", cfg.Listen)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return srv.Shutdown(ctx)
+		},
+	})
+}
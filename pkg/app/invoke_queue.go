@@ -2,7 +2,6 @@ package app
 
 import (
 	"context"
-	"time"
 	"wangzhiqiang/skeleton/config"
 	"wangzhiqiang/skeleton/pkg/logger"
 	"wangzhiqiang/skeleton/pkg/queue"
@@ -21,8 +20,16 @@ type InvokeQueue struct {
 func NewInvokeQueue(app InvokeQueue) {
 	app.Lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
-			startInterval := time.Second
-			app.Logger.Infof("[InvokeQueue] Starting queue... interval: %v\n", startInterval)
+			cfg := app.Config.Queue
+			startInterval := cfg.GetPollInterval()
+			app.Logger.Infof("[InvokeQueue] Starting queue... workers: %d, batch: %d, interval: %v ~ %v\n",
+				cfg.Workers, cfg.BatchSize, startInterval, cfg.GetMaxPollInterval())
+			app.Queue.Use(
+				queue.Recovery(),
+				queue.RequestID(),
+				queue.Logging(app.Logger),
+				queue.Timeout(cfg.GetTimeout()),
+			)
 			go app.Queue.Start(appContext, startInterval)
 			app.Logger.Infof("[InvokeQueue] Queue started successfully")
 			return nil
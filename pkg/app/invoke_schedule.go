@@ -0,0 +1,45 @@
+package app
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"wangzhiqiang/skeleton/config"
+	"wangzhiqiang/skeleton/pkg/logger"
+	"wangzhiqiang/skeleton/pkg/queue"
+
+	"go.uber.org/fx"
+)
+
+type InvokeSchedule struct {
+	fx.In
+	Lc     fx.Lifecycle
+	Logger logger.ILogger
+	Config *config.Config
+	DB     *gorm.DB
+	Queue  queue.IQueue
+}
+
+func NewInvokeSchedule(app InvokeSchedule) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	app.Lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			entries := queue.GetSchedules()
+			app.Logger.Infof("[InvokeSchedule] Starting scheduler... entries: %d", len(entries))
+			scheduler := queue.NewScheduler(app.DB, app.Queue, entries)
+			go func() {
+				defer close(done)
+				scheduler.Run(ctx)
+			}()
+			app.Logger.Infof("[InvokeSchedule] Scheduler started successfully")
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			app.Logger.Infof("[InvokeSchedule] Stopping scheduler...")
+			cancel()
+			<-done
+			app.Logger.Infof("[InvokeSchedule] Scheduler stopped successfully")
+			return nil
+		},
+	})
+}
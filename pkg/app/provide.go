@@ -2,24 +2,47 @@ package app
 
 import (
 	"github.com/casbin/casbin/v2"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
+	"time"
 	"wangzhiqiang/skeleton/config"
 	"wangzhiqiang/skeleton/pkg/casbinx"
+	"wangzhiqiang/skeleton/pkg/cryptox"
 	"wangzhiqiang/skeleton/pkg/database"
 	"wangzhiqiang/skeleton/pkg/httpx"
 	"wangzhiqiang/skeleton/pkg/jwts"
 	"wangzhiqiang/skeleton/pkg/logger"
+	"wangzhiqiang/skeleton/pkg/oidcx"
 	"wangzhiqiang/skeleton/pkg/queue"
+	"wangzhiqiang/skeleton/pkg/redisx"
 )
 
 func ProvideLogger(cfg *config.Config) (logger.ILogger, error) {
 	return logger.Init(cfg.Logger)
 }
 
-func ProvideDatabase(cfg *config.Config) (*gorm.DB, error) {
+// ProvideKeyring 提供字段加密密钥环，没有加密字段时可以不配置密钥
+func ProvideKeyring(cfg *config.Config) (*cryptox.Keyring, error) {
+	if (cfg.Encryption == nil || len(cfg.Encryption.Keys) == 0) && !database.HasEncryptedModels() {
+		return nil, nil
+	}
+	return cryptox.NewKeyring(cfg.Encryption)
+}
+
+// ProvideDatabase 加密字段读写依赖密钥环，先设置密钥环再提供数据库
+func ProvideDatabase(cfg *config.Config, keyring *cryptox.Keyring) (*gorm.DB, error) {
+	database.SetKeyring(keyring)
 	return database.Init(cfg.Database)
 }
 
+// ProvideRedis 未配置 redis 时返回 nil
+func ProvideRedis(cfg *config.Config) *redis.Client {
+	if cfg.Redis == nil {
+		return nil
+	}
+	return redisx.New(cfg.Redis)
+}
+
 func ProvideEnforcer(db *gorm.DB) (*casbin.Enforcer, error) {
 	return casbinx.New(&casbinx.Config{DB: db})
 }
@@ -28,15 +51,37 @@ func ProvideHTTPServer(cfg *config.Config) *httpx.HTTP {
 	return httpx.NewHTTP(cfg.Server)
 }
 
-func ProvideJWT(cfg *config.Config) *jwts.JWT {
-	return jwts.NewJWT(cfg.JWT)
+func ProvideJWT(db *gorm.DB, rdb *redis.Client, cfg *config.Config) (*jwts.JWT, error) {
+	j, err := jwts.NewJWT(cfg.JWT)
+	if err != nil {
+		return nil, err
+	}
+	// 会话存储，配置 Redis 时缓存会话状态
+	var client redis.UniversalClient
+	if rdb != nil {
+		client = rdb
+	}
+	j.SetSessionStore(jwts.NewSessionStore(db, client))
+	return j, nil
+}
+
+func ProvideOAuth(cfg *config.Config) *oidcx.Providers {
+	return oidcx.NewProviders(cfg.OAuth)
 }
 
-func ProvideQueue(db *gorm.DB, cfg *config.Config) (queue.IQueue, error) {
+func ProvideQueue(db *gorm.DB, rdb *redis.Client, cfg *config.Config) (queue.IQueue, error) {
 	if cfg.Queue == nil {
 		cfg.Queue = &queue.Config{}
 	}
-	q, err := queue.New(cfg.Queue, db)
+	// 未配置时与 HTTP 服务关闭超时保持一致
+	if cfg.Queue.DrainTimeout <= 0 {
+		cfg.Queue.DrainTimeout = int(ShutdownTimeout / time.Second)
+	}
+	var client redis.UniversalClient
+	if rdb != nil {
+		client = rdb
+	}
+	q, err := queue.New(cfg.Queue, db, client)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,95 @@
+package cryptox
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2Params argon2id 参数
+type Argon2Params struct {
+	Memory      uint32 `yaml:"memory" json:"memory,omitempty"`           // 内存（单位：KiB），默认 65536
+	Iterations  uint32 `yaml:"iterations" json:"iterations,omitempty"`   // 迭代次数，默认 3
+	Parallelism uint8  `yaml:"parallelism" json:"parallelism,omitempty"` // 并行度，默认 2
+	SaltLength  uint32 `yaml:"salt_length" json:"salt_length,omitempty"` // 盐长度（字节），默认 16
+	KeyLength   uint32 `yaml:"key_length" json:"key_length,omitempty"`   // 哈希长度（字节），默认 32
+}
+
+// Argon2id argon2id 哈希，格式为 $argon2id$v=19$m=65536,t=3,p=2$salt$hash
+type Argon2id struct {
+	params Argon2Params
+}
+
+// NewArgon2id 未设置的参数使用默认值
+func NewArgon2id(params Argon2Params) *Argon2id {
+	if params.Memory == 0 {
+		params.Memory = 64 * 1024
+	}
+	if params.Iterations == 0 {
+		params.Iterations = 3
+	}
+	if params.Parallelism == 0 {
+		params.Parallelism = 2
+	}
+	if params.SaltLength == 0 {
+		params.SaltLength = 16
+	}
+	if params.KeyLength == 0 {
+		params.KeyLength = 32
+	}
+	return &Argon2id{params: params}
+}
+
+func (a *Argon2id) Hash(password string) (string, error) {
+	s, err := salt(int(a.params.SaltLength))
+	if err != nil {
+		return "", err
+	}
+	p := a.params
+	key := argon2.IDKey([]byte(password), s, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(s), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (a *Argon2id) Verify(password, hashed string) (bool, error) {
+	p, s, key, err := decodeArgon2id(hashed)
+	if err != nil {
+		return false, err
+	}
+	other := argon2.IDKey([]byte(password), s, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func (a *Argon2id) NeedsRehash(hashed string) bool {
+	p, s, key, err := decodeArgon2id(hashed)
+	if err != nil {
+		return true
+	}
+	return p.Memory != a.params.Memory || p.Iterations != a.params.Iterations || p.Parallelism != a.params.Parallelism ||
+		uint32(len(s)) != a.params.SaltLength || uint32(len(key)) != a.params.KeyLength
+}
+
+// decodeArgon2id 解析 PHC 格式的 argon2id 哈希
+func decodeArgon2id(hashed string) (p Argon2Params, s, key []byte, err error) {
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 || parts[1] != AlgArgon2id {
+		return p, nil, nil, ErrInvalidHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if s, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		return p, nil, nil, ErrInvalidHash
+	}
+	return p, s, key, nil
+}
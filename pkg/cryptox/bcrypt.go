@@ -0,0 +1,44 @@
+package cryptox
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Bcrypt bcrypt 哈希，格式为 $2a$cost$salthash
+type Bcrypt struct {
+	Cost int
+}
+
+// NewBcrypt cost 为 0 时使用默认值 10
+func NewBcrypt(cost int) *Bcrypt {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return &Bcrypt{Cost: cost}
+}
+
+func (b *Bcrypt) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	return string(hash), err
+}
+
+func (b *Bcrypt) Verify(password, hashed string) (bool, error) {
+	if Identify(hashed) != AlgBcrypt {
+		return false, ErrInvalidHash
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b *Bcrypt) NeedsRehash(hashed string) bool {
+	if Identify(hashed) != AlgBcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hashed))
+	return err != nil || cost != b.Cost
+}
@@ -1,17 +1,99 @@
 package cryptox
 
 import (
-	"golang.org/x/crypto/bcrypt"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
 )
 
-// HashMake 生成密码哈希
-func HashMake(raw string) string {
-	pwd := []byte(raw)
-	hash, _ := bcrypt.GenerateFromPassword(pwd, bcrypt.DefaultCost)
-	return string(hash)
+const (
+	AlgBcrypt   = "bcrypt"
+	AlgArgon2id = "argon2id"
+	AlgScrypt   = "scrypt"
+)
+
+var ErrInvalidHash = errors.New("cryptox: invalid password hash")
+
+// Hasher 密码哈希算法
+//
+//	哈希使用 PHC 格式，以 $算法$ 开头并带上参数，校验时按前缀选择算法，参数从哈希中读取
+type Hasher interface {
+	// Hash 生成哈希
+	Hash(password string) (string, error)
+	// Verify 校验密码，哈希不是该算法生成时返回 ErrInvalidHash
+	Verify(password, hashed string) (bool, error)
+	// NeedsRehash 哈希不是该算法或参数与当前配置不一致时返回 true
+	NeedsRehash(hashed string) bool
+}
+
+// HashConfig 密码哈希配置
+type HashConfig struct {
+	Algorithm  string       `yaml:"algorithm" json:"algorithm,omitempty"`     // 新密码使用的算法：bcrypt、argon2id、scrypt，默认 bcrypt
+	BcryptCost int          `yaml:"bcrypt_cost" json:"bcrypt_cost,omitempty"` // bcrypt cost，默认 10
+	Argon2id   Argon2Params `yaml:"argon2id" json:"argon2id,omitempty"`       // argon2id 参数
+	Scrypt     ScryptParams `yaml:"scrypt" json:"scrypt,omitempty"`           // scrypt 参数
+}
+
+// Hasher 返回配置的哈希算法
+func (c *HashConfig) Hasher() (Hasher, error) {
+	switch c.Algorithm {
+	case "", AlgBcrypt:
+		return NewBcrypt(c.BcryptCost), nil
+	case AlgArgon2id:
+		return NewArgon2id(c.Argon2id), nil
+	case AlgScrypt:
+		return NewScrypt(c.Scrypt), nil
+	}
+	return nil, fmt.Errorf("cryptox: unsupported hash algorithm %q", c.Algorithm)
 }
 
-// HashVerify 验证密码是否匹配哈希
+// Identify 根据哈希前缀识别算法，无法识别时返回空字符串
+func Identify(hashed string) string {
+	switch {
+	case strings.HasPrefix(hashed, "$argon2id$"):
+		return AlgArgon2id
+	case strings.HasPrefix(hashed, "$scrypt$"):
+		return AlgScrypt
+	case strings.HasPrefix(hashed, "$2a$"), strings.HasPrefix(hashed, "$2b$"), strings.HasPrefix(hashed, "$2y$"):
+		return AlgBcrypt
+	}
+	return ""
+}
+
+// HashMake 使用默认参数的 bcrypt 生成密码哈希
+func HashMake(raw string) (string, error) {
+	return NewBcrypt(0).Hash(raw)
+}
+
+// HashVerify 按哈希前缀选择算法验证密码
 func HashVerify(raw, hashed string) bool {
-	return bcrypt.CompareHashAndPassword([]byte(hashed), []byte(raw)) == nil
+	var h Hasher
+	switch Identify(hashed) {
+	case AlgBcrypt:
+		h = NewBcrypt(0)
+	case AlgArgon2id:
+		h = NewArgon2id(Argon2Params{})
+	case AlgScrypt:
+		h = NewScrypt(ScryptParams{})
+	default:
+		return false
+	}
+	ok, err := h.Verify(raw, hashed)
+	return err == nil && ok
+}
+
+// HashCheck 验证密码，并返回是否需要用 current 重新生成哈希，用于登录时逐步迁移算法和参数
+func HashCheck(current Hasher, raw, hashed string) (ok, needsRehash bool) {
+	if !HashVerify(raw, hashed) {
+		return false, false
+	}
+	return true, current.NeedsRehash(hashed)
+}
+
+// salt 生成随机盐
+func salt(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	return b, err
 }
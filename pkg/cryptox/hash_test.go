@@ -0,0 +1,81 @@
+package cryptox
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasher(t *testing.T) {
+	hashers := map[string]Hasher{
+		AlgBcrypt:   NewBcrypt(4),
+		AlgArgon2id: NewArgon2id(Argon2Params{Memory: 1024, Iterations: 1}),
+		AlgScrypt:   NewScrypt(ScryptParams{N: 1 << 10}),
+	}
+	for alg, h := range hashers {
+		t.Run(alg, func(t *testing.T) {
+			hashed, err := h.Hash("secret")
+			assert.NoError(t, err)
+			assert.Equal(t, alg, Identify(hashed))
+
+			ok, err := h.Verify("secret", hashed)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			ok, err = h.Verify("wrong", hashed)
+			assert.NoError(t, err)
+			assert.False(t, ok)
+
+			// 按前缀分发
+			assert.True(t, HashVerify("secret", hashed))
+			assert.False(t, HashVerify("wrong", hashed))
+			assert.False(t, h.NeedsRehash(hashed))
+
+			// 不是该算法生成的哈希
+			for other, oh := range hashers {
+				if other != alg {
+					assert.True(t, oh.NeedsRehash(hashed))
+					_, err := oh.Verify("secret", hashed)
+					assert.ErrorIs(t, err, ErrInvalidHash)
+				}
+			}
+		})
+	}
+}
+
+func TestHasher_PHC(t *testing.T) {
+	// 参数按 PHC 格式写入哈希
+	hashed, err := NewArgon2id(Argon2Params{Memory: 1024, Iterations: 2, Parallelism: 1}).Hash("password")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hashed, "$argon2id$v=19$m=1024,t=2,p=1$"))
+
+	hashed, err = NewScrypt(ScryptParams{N: 1 << 10, R: 8, P: 1}).Hash("password")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hashed, "$scrypt$ln=10,r=8,p=1$"))
+
+	assert.False(t, HashVerify("password", "$argon2id$v=19$m=1024,t=2,p=1$bad"))
+	assert.False(t, HashVerify("password", "plain"))
+	assert.False(t, HashVerify("", ""))
+}
+
+func TestHashCheck(t *testing.T) {
+	old, err := NewBcrypt(4).Hash("secret")
+	assert.NoError(t, err)
+	current := NewArgon2id(Argon2Params{Memory: 1024, Iterations: 1})
+
+	// 旧算法的哈希仍然可以登录，并提示迁移到当前算法
+	ok, rehash := HashCheck(current, "secret", old)
+	assert.True(t, ok)
+	assert.True(t, rehash)
+	ok, rehash = HashCheck(current, "wrong", old)
+	assert.False(t, ok)
+	assert.False(t, rehash)
+
+	// 参数变化同样需要重新生成
+	hashed, err := current.Hash("secret")
+	assert.NoError(t, err)
+	_, rehash = HashCheck(current, "secret", hashed)
+	assert.False(t, rehash)
+	_, rehash = HashCheck(NewArgon2id(Argon2Params{Memory: 2048, Iterations: 1}), "secret", hashed)
+	assert.True(t, rehash)
+}
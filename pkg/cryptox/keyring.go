@@ -0,0 +1,176 @@
+package cryptox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// encryptedPrefix 密文前缀，格式为 enc:v1:密钥版本:加密后的数据密钥:加密后的数据
+const encryptedPrefix = "enc:v1:"
+
+var (
+	ErrInvalidCiphertext = errors.New("cryptox: invalid ciphertext")
+	ErrKeyNotFound       = errors.New("cryptox: encryption key not found")
+)
+
+// EncryptionConfig 字段加密配置
+type EncryptionConfig struct {
+	ActiveKey string           `yaml:"active_key" json:"active_key,omitempty"` // 加密使用的密钥版本，为空时使用第一个密钥
+	Keys      []*EncryptionKey `yaml:"keys" json:"keys,omitempty"`             // 主密钥，轮换时保留旧密钥用于解密
+}
+
+// EncryptionKey 主密钥
+type EncryptionKey struct {
+	Version string `yaml:"version" json:"version,omitempty"` // 密钥版本，写入密文，解密时按版本查找密钥
+	Key     string `yaml:"key" json:"key,omitempty"`         // base64 编码的 32 字节密钥，可用 openssl rand -base64 32 生成
+}
+
+// Keyring 信封加密密钥环
+//
+//	每次加密生成随机数据密钥，用数据密钥 AES-256-GCM 加密数据，再用当前版本的主密钥加密数据密钥
+//	密文带有主密钥版本，轮换主密钥后旧密文仍可解密，可通过 NeedsReencrypt 判断是否需要重新加密
+type Keyring struct {
+	keys   map[string]cipher.AEAD
+	active string
+}
+
+// NewKeyring 根据配置加载主密钥
+func NewKeyring(cfg *EncryptionConfig) (*Keyring, error) {
+	if cfg == nil || len(cfg.Keys) == 0 {
+		return nil, errors.New("encryption: keys is required, generate a key with `openssl rand -base64 32` and add it to encryption.keys")
+	}
+	k := &Keyring{keys: make(map[string]cipher.AEAD), active: cfg.ActiveKey}
+	for _, key := range cfg.Keys {
+		if key.Version == "" || strings.Contains(key.Version, ":") {
+			return nil, fmt.Errorf("encryption key %q: invalid version", key.Version)
+		}
+		if _, ok := k.keys[key.Version]; ok {
+			return nil, fmt.Errorf("encryption key %q: duplicate version", key.Version)
+		}
+		raw, err := base64.StdEncoding.DecodeString(key.Key)
+		if err != nil || len(raw) != 32 {
+			return nil, fmt.Errorf("encryption key %q: key must be 32 bytes base64, generate one with `openssl rand -base64 32`", key.Version)
+		}
+		aead, err := newAEAD(raw)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[key.Version] = aead
+	}
+	if k.active == "" {
+		k.active = cfg.Keys[0].Version
+	}
+	if _, ok := k.keys[k.active]; !ok {
+		return nil, fmt.Errorf("encryption: active key %q not found", k.active)
+	}
+	return k, nil
+}
+
+// ActiveVersion 返回当前加密使用的密钥版本
+func (k *Keyring) ActiveVersion() string {
+	return k.active
+}
+
+// Encrypt 使用当前主密钥加密
+func (k *Keyring) Encrypt(plaintext []byte) (string, error) {
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+	header := encryptedPrefix + k.active
+	wrapped, err := seal(k.keys[k.active], dek, []byte(header))
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+	data, err := seal(aead, plaintext, []byte(header))
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawStdEncoding
+	return header + ":" + enc.EncodeToString(wrapped) + ":" + enc.EncodeToString(data), nil
+}
+
+// Decrypt 按密文中的密钥版本解密
+func (k *Keyring) Decrypt(ciphertext string) ([]byte, error) {
+	if !IsEncrypted(ciphertext) {
+		return nil, ErrInvalidCiphertext
+	}
+	parts := strings.Split(strings.TrimPrefix(ciphertext, encryptedPrefix), ":")
+	if len(parts) != 3 {
+		return nil, ErrInvalidCiphertext
+	}
+	kek, ok := k.keys[parts[0]]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, parts[0])
+	}
+	enc := base64.RawStdEncoding
+	wrapped, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	data, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	header := []byte(encryptedPrefix + parts[0])
+	dek, err := open(kek, wrapped, header)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+	return open(aead, data, header)
+}
+
+// NeedsReencrypt 不是密文或不是当前主密钥加密时返回 true
+func (k *Keyring) NeedsReencrypt(value string) bool {
+	if !IsEncrypted(value) {
+		return true
+	}
+	version, _, _ := strings.Cut(strings.TrimPrefix(value, encryptedPrefix), ":")
+	return version != k.active
+}
+
+// IsEncrypted 是否为 Keyring 生成的密文
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal 加密，随机 nonce 放在密文前面
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(aead cipher.AEAD, data, additional []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], additional)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return plaintext, nil
+}
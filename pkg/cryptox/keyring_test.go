@@ -0,0 +1,69 @@
+package cryptox
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newKey(t *testing.T) string {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	assert.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func TestKeyring(t *testing.T) {
+	k1, k2 := &EncryptionKey{Version: "1", Key: newKey(t)}, &EncryptionKey{Version: "2", Key: newKey(t)}
+	old, err := NewKeyring(&EncryptionConfig{Keys: []*EncryptionKey{k1}})
+	assert.NoError(t, err)
+
+	ciphertext, err := old.Encrypt([]byte("13800138000"))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(ciphertext, "enc:v1:1:"))
+	assert.NotContains(t, ciphertext, "13800138000")
+	plaintext, err := old.Decrypt(ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, "13800138000", string(plaintext))
+
+	// 每次加密使用不同的数据密钥和 nonce
+	other, err := old.Encrypt([]byte("13800138000"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, ciphertext, other)
+
+	// 轮换后旧密文仍可解密，并提示重新加密
+	rotated, err := NewKeyring(&EncryptionConfig{ActiveKey: "2", Keys: []*EncryptionKey{k1, k2}})
+	assert.NoError(t, err)
+	plaintext, err = rotated.Decrypt(ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, "13800138000", string(plaintext))
+	assert.True(t, rotated.NeedsReencrypt(ciphertext))
+	assert.True(t, rotated.NeedsReencrypt("13800138000"))
+	reencrypted, err := rotated.Encrypt(plaintext)
+	assert.NoError(t, err)
+	assert.False(t, rotated.NeedsReencrypt(reencrypted))
+
+	// 删除旧密钥后无法解密
+	_, err = old.Decrypt(reencrypted)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// 篡改密文或版本
+	_, err = rotated.Decrypt(ciphertext[:len(ciphertext)-2] + "AA")
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+	_, err = rotated.Decrypt(strings.Replace(reencrypted, "enc:v1:2:", "enc:v1:1:", 1))
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+}
+
+func TestNewKeyring(t *testing.T) {
+	_, err := NewKeyring(&EncryptionConfig{})
+	assert.Error(t, err)
+	_, err = NewKeyring(&EncryptionConfig{Keys: []*EncryptionKey{{Version: "1", Key: "short"}}})
+	assert.Error(t, err)
+	_, err = NewKeyring(&EncryptionConfig{ActiveKey: "2", Keys: []*EncryptionKey{{Version: "1", Key: newKey(t)}}})
+	assert.Error(t, err)
+	_, err = NewKeyring(&EncryptionConfig{Keys: []*EncryptionKey{{Version: "a:b", Key: newKey(t)}}})
+	assert.Error(t, err)
+}
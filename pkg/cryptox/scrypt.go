@@ -0,0 +1,103 @@
+package cryptox
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"math/bits"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ScryptParams scrypt 参数
+type ScryptParams struct {
+	N          int `yaml:"n" json:"n,omitempty"`                     // CPU/内存开销，必须是 2 的幂，默认 32768
+	R          int `yaml:"r" json:"r,omitempty"`                     // 块大小，默认 8
+	P          int `yaml:"p" json:"p,omitempty"`                     // 并行度，默认 1
+	SaltLength int `yaml:"salt_length" json:"salt_length,omitempty"` // 盐长度（字节），默认 16
+	KeyLength  int `yaml:"key_length" json:"key_length,omitempty"`   // 哈希长度（字节），默认 32
+}
+
+// Scrypt scrypt 哈希，格式为 $scrypt$ln=15,r=8,p=1$salt$hash，ln 为 log2(N)
+type Scrypt struct {
+	params ScryptParams
+}
+
+// NewScrypt 未设置的参数使用默认值
+func NewScrypt(params ScryptParams) *Scrypt {
+	if params.N == 0 {
+		params.N = 1 << 15
+	}
+	if params.R == 0 {
+		params.R = 8
+	}
+	if params.P == 0 {
+		params.P = 1
+	}
+	if params.SaltLength == 0 {
+		params.SaltLength = 16
+	}
+	if params.KeyLength == 0 {
+		params.KeyLength = 32
+	}
+	return &Scrypt{params: params}
+}
+
+func (s *Scrypt) Hash(password string) (string, error) {
+	p := s.params
+	if p.N < 2 || p.N&(p.N-1) != 0 {
+		return "", fmt.Errorf("cryptox: scrypt N must be a power of 2")
+	}
+	sa, err := salt(p.SaltLength)
+	if err != nil {
+		return "", err
+	}
+	key, err := scrypt.Key([]byte(password), sa, p.N, p.R, p.P, p.KeyLength)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s$%s", bits.TrailingZeros(uint(p.N)), p.R, p.P,
+		base64.RawStdEncoding.EncodeToString(sa), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (s *Scrypt) Verify(password, hashed string) (bool, error) {
+	p, sa, key, err := decodeScrypt(hashed)
+	if err != nil {
+		return false, err
+	}
+	other, err := scrypt.Key([]byte(password), sa, p.N, p.R, p.P, len(key))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func (s *Scrypt) NeedsRehash(hashed string) bool {
+	p, sa, key, err := decodeScrypt(hashed)
+	if err != nil {
+		return true
+	}
+	return p.N != s.params.N || p.R != s.params.R || p.P != s.params.P ||
+		len(sa) != s.params.SaltLength || len(key) != s.params.KeyLength
+}
+
+// decodeScrypt 解析 PHC 格式的 scrypt 哈希
+func decodeScrypt(hashed string) (p ScryptParams, sa, key []byte, err error) {
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 5 || parts[1] != AlgScrypt {
+		return p, nil, nil, ErrInvalidHash
+	}
+	var ln int
+	if _, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &ln, &p.R, &p.P); err != nil || ln < 1 || ln > 30 {
+		return p, nil, nil, ErrInvalidHash
+	}
+	p.N = 1 << ln
+	if sa, err = base64.RawStdEncoding.DecodeString(parts[3]); err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil || len(key) == 0 {
+		return p, nil, nil, ErrInvalidHash
+	}
+	return p, sa, key, nil
+}
@@ -0,0 +1,231 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"wangzhiqiang/skeleton/pkg/cryptox"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	keyring atomic.Pointer[cryptox.Keyring]
+
+	encryptedLock   sync.Mutex
+	encryptedModels []any
+)
+
+func init() {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+}
+
+// SetKeyring 设置加密字段使用的密钥环
+func SetKeyring(k *cryptox.Keyring) {
+	keyring.Store(k)
+}
+
+// RegisterEncryptedModel 注册包含加密字段的模型，Reencrypt 轮换密钥时处理
+func RegisterEncryptedModel(models ...any) {
+	encryptedLock.Lock()
+	defer encryptedLock.Unlock()
+	encryptedModels = append(encryptedModels, models...)
+}
+
+// HasEncryptedModels 是否注册了包含加密字段的模型
+func HasEncryptedModels() bool {
+	encryptedLock.Lock()
+	defer encryptedLock.Unlock()
+	return len(encryptedModels) > 0
+}
+
+// EncryptedSerializer 字段加密序列化器，使用 `gorm:"serializer:encrypted"` 的字段写入时加密，读取时解密
+//
+//	字段类型为 string 或 []byte 时直接加密，其他类型先转成 JSON，列类型需要足够存放密文
+//	空值不加密；读取到未加密的旧数据时原样返回，之后通过 Reencrypt 加密
+type EncryptedSerializer struct{}
+
+// Scan 解密数据库中的值
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue any) error {
+	fieldValue := reflect.New(field.FieldType)
+	if dbValue != nil {
+		var raw string
+		switch v := dbValue.(type) {
+		case []byte:
+			raw = string(v)
+		case string:
+			raw = v
+		default:
+			return fmt.Errorf("failed to decrypt value: %#v", dbValue)
+		}
+		data, err := decrypt(raw)
+		if err != nil {
+			return fmt.Errorf("decrypt %s.%s: %w", field.Schema.Table, field.DBName, err)
+		}
+		if len(data) > 0 {
+			switch {
+			case field.FieldType.Kind() == reflect.String:
+				fieldValue.Elem().SetString(string(data))
+			case field.FieldType == reflect.TypeOf([]byte(nil)):
+				fieldValue.Elem().SetBytes(data)
+			default:
+				if err := json.Unmarshal(data, fieldValue.Interface()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return nil
+}
+
+// Value 加密写入数据库的值
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue any) (any, error) {
+	var data []byte
+	switch v := fieldValue.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		if rv := reflect.ValueOf(fieldValue); !rv.IsValid() || (rv.Kind() == reflect.Ptr && rv.IsNil()) {
+			return nil, nil
+		}
+		var err error
+		if data, err = json.Marshal(fieldValue); err != nil {
+			return nil, err
+		}
+	}
+	if len(data) == 0 {
+		return "", nil
+	}
+	k := keyring.Load()
+	if k == nil {
+		return nil, errors.New("encryption keyring is not configured")
+	}
+	return k.Encrypt(data)
+}
+
+// decrypt 解密，未加密的值原样返回
+func decrypt(raw string) ([]byte, error) {
+	if !cryptox.IsEncrypted(raw) {
+		return []byte(raw), nil
+	}
+	k := keyring.Load()
+	if k == nil {
+		return nil, errors.New("encryption keyring is not configured")
+	}
+	return k.Decrypt(raw)
+}
+
+// Reencrypt 使用当前密钥重新加密已注册模型的加密字段，包括未加密的旧数据，返回更新的行数
+//
+//	按主键分批读取原始值，只更新不是当前密钥加密的字段；轮换密钥时先把新密钥设为 active_key，
+//	执行完成后才能从配置中删除旧密钥
+func Reencrypt(db *gorm.DB, batchSize int) (int64, error) {
+	k := keyring.Load()
+	if k == nil {
+		return 0, errors.New("encryption keyring is not configured")
+	}
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	encryptedLock.Lock()
+	models := append([]any(nil), encryptedModels...)
+	encryptedLock.Unlock()
+
+	var total int64
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return total, err
+		}
+		sch := stmt.Schema
+		pk := sch.PrioritizedPrimaryField
+		if pk == nil {
+			return total, fmt.Errorf("reencrypt %s: primary key is required", sch.Table)
+		}
+		var columns []string
+		for _, field := range sch.Fields {
+			if field.DBName != "" && field.TagSettings["SERIALIZER"] == "encrypted" {
+				columns = append(columns, field.DBName)
+			}
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		n, err := reencryptTable(db, k, sch.Table, pk.DBName, columns, batchSize)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("reencrypt %s: %w", sch.Table, err)
+		}
+	}
+	return total, nil
+}
+
+// reencryptTable 直接读写列的原始值，不经过序列化器，软删除的数据同样处理
+func reencryptTable(db *gorm.DB, k *cryptox.Keyring, table, pk string, columns []string, batchSize int) (int64, error) {
+	var (
+		total int64
+		last  any = 0
+	)
+	for {
+		var rows []map[string]any
+		if err := db.Table(table).Select(append([]string{pk}, columns...)).
+			Where(pk+" > ?", last).Order(pk).Limit(batchSize).
+			Find(&rows).Error; err != nil {
+			return total, err
+		}
+		for _, row := range rows {
+			old := map[string]any{}
+			updates := map[string]any{}
+			for _, column := range columns {
+				raw, ok := rawString(row[column])
+				if !ok || raw == "" || !k.NeedsReencrypt(raw) {
+					continue
+				}
+				data, err := decrypt(raw)
+				if err != nil {
+					return total, err
+				}
+				if updates[column], err = k.Encrypt(data); err != nil {
+					return total, err
+				}
+				old[column] = raw
+			}
+			if len(updates) == 0 {
+				continue
+			}
+			// 条件更新，避免覆盖执行期间写入的新值
+			result := db.Table(table).Where(pk+" = ?", row[pk]).Where(old).UpdateColumns(updates)
+			if result.Error != nil {
+				return total, result.Error
+			}
+			total += result.RowsAffected
+		}
+		if len(rows) < batchSize {
+			return total, nil
+		}
+		last = rows[len(rows)-1][pk]
+	}
+}
+
+// rawString 按列类型扫描到 map 时可能是 *any，例如 sqlite 的 longtext
+func rawString(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	case *any:
+		if v != nil {
+			return rawString(*v)
+		}
+	}
+	return "", false
+}
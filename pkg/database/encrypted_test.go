@@ -0,0 +1,88 @@
+package database
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"wangzhiqiang/skeleton/pkg/cryptox"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type encryptedUser struct {
+	BaseModel
+	Name   string            `gorm:"type:varchar(50)"`
+	Phone  string            `gorm:"type:varchar(255);serializer:encrypted"`
+	Secret []byte            `gorm:"type:longtext;serializer:encrypted"`
+	Extra  map[string]string `gorm:"type:longtext;serializer:encrypted"`
+}
+
+func newTestKeyring(t *testing.T, active string, versions ...string) *cryptox.Keyring {
+	cfg := &cryptox.EncryptionConfig{ActiveKey: active}
+	for _, v := range versions {
+		cfg.Keys = append(cfg.Keys, &cryptox.EncryptionKey{Version: v, Key: testKeys[v]})
+	}
+	k, err := cryptox.NewKeyring(cfg)
+	require.NoError(t, err)
+	return k
+}
+
+var testKeys = map[string]string{}
+
+func init() {
+	for _, v := range []string{"1", "2"} {
+		b := make([]byte, 32)
+		_, _ = rand.Read(b)
+		testKeys[v] = base64.StdEncoding.EncodeToString(b)
+	}
+}
+
+func TestEncryptedSerializer(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&encryptedUser{}))
+	SetKeyring(newTestKeyring(t, "1", "1"))
+
+	user := encryptedUser{Name: "a", Phone: "13800138000", Secret: []byte("secret"), Extra: map[string]string{"id": "x"}}
+	require.NoError(t, db.Create(&user).Error)
+	// 旧数据未加密
+	require.NoError(t, db.Create(&encryptedUser{Name: "b", Phone: "13900139000"}).Error)
+	require.NoError(t, db.Table("encrypted_users").Where("name = ?", "b").UpdateColumn("phone", "13900139000").Error)
+
+	var raw string
+	require.NoError(t, db.Table("encrypted_users").Where("id = ?", user.ID).Pluck("phone", &raw).Error)
+	assert.True(t, strings.HasPrefix(raw, "enc:v1:1:"))
+
+	var got []encryptedUser
+	require.NoError(t, db.Order("id").Find(&got).Error)
+	assert.Equal(t, "13800138000", got[0].Phone)
+	assert.Equal(t, []byte("secret"), got[0].Secret)
+	assert.Equal(t, map[string]string{"id": "x"}, got[0].Extra)
+	assert.Equal(t, "13900139000", got[1].Phone)
+	assert.Nil(t, got[1].Extra)
+
+	// 轮换密钥后重新加密，只保留新密钥仍可读取
+	RegisterEncryptedModel(&encryptedUser{})
+	SetKeyring(newTestKeyring(t, "2", "1", "2"))
+	n, err := Reencrypt(db, 1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	n, err = Reencrypt(db, 1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, n)
+
+	SetKeyring(newTestKeyring(t, "2", "2"))
+	got = nil
+	require.NoError(t, db.Order("id").Find(&got).Error)
+	assert.Equal(t, "13800138000", got[0].Phone)
+	assert.Equal(t, map[string]string{"id": "x"}, got[0].Extra)
+	assert.Equal(t, "13900139000", got[1].Phone)
+	require.NoError(t, db.Table("encrypted_users").Where("id = ?", got[1].ID).Pluck("phone", &raw).Error)
+	assert.True(t, strings.HasPrefix(raw, "enc:v1:2:"))
+}
@@ -41,6 +41,8 @@ func (h *HTTP) newGin(ctx context.Context) *gin.Engine {
 	engine.Use(mws.Security())
 	//request
 	engine.Use(mws.RequestID())
+	// 请求指标
+	engine.Use(mws.Metrics())
 	// 使用会话中间件
 	engine.Use(mws.Session(h.config.Session))
 	// 使用 Gzip 压缩中间件
@@ -0,0 +1,32 @@
+package httpx
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsConfig Prometheus 指标配置
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled" json:"enabled,omitempty"` // 是否暴露指标，默认关闭
+	Token   string `yaml:"token" json:"token,omitempty"`     // 采集时需要携带 Authorization: Bearer <token>，为空时不校验，应只在内网开放
+	Listen  string `yaml:"listen" json:"listen,omitempty"`   // 独立的指标监听地址，例如 127.0.0.1:9091，用于 queue:start 等没有 HTTP 服务的进程
+}
+
+// MetricsHandler 返回 Prometheus 指标处理器，配置 token 时校验 Bearer token
+func MetricsHandler(token string) http.Handler {
+	handler := promhttp.Handler()
+	if token == "" {
+		return handler
+	}
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
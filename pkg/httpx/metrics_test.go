@@ -0,0 +1,27 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	scrape := func(h http.Handler, auth string) int {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusOK, scrape(MetricsHandler(""), ""))
+
+	h := MetricsHandler("secret")
+	assert.Equal(t, http.StatusUnauthorized, scrape(h, ""))
+	assert.Equal(t, http.StatusUnauthorized, scrape(h, "Bearer other"))
+	assert.Equal(t, http.StatusOK, scrape(h, "Bearer secret"))
+}
@@ -3,9 +3,11 @@ package jwts
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 // TokenType
@@ -16,8 +18,10 @@ type TokenType string
 var _ JWTService = (*JWT)(nil)
 
 const (
-	AccessTokenType  TokenType = "access_token"
-	RefreshTokenType TokenType = "refresh_token"
+	AccessTokenType     TokenType = "access_token"
+	RefreshTokenType    TokenType = "refresh_token"
+	MFAPendingTokenType TokenType = "mfa_pending" // 密码校验通过、等待两步验证的 token
+	APIKeyTokenType     TokenType = "api_key"     // API Key 认证，不签发 JWT，只用于标识请求的认证方式
 )
 
 var (
@@ -28,6 +32,10 @@ var (
 	TokenSignatureInvalid = errors.New("无效签名")
 	TokenInvalid          = errors.New("无法处理此token")
 	ErrNotRefreshToken    = errors.New("不是 refresh token，无法续签")
+	ErrRefreshTokenReused = errors.New("refresh token 已被使用，请重新登录")
+	ErrNotAccessToken     = errors.New("不是 access token")
+	TokenInvalidIssuer    = errors.New("token签发者无效")
+	TokenInvalidAudience  = errors.New("token受众无效")
 )
 
 // IUser
@@ -42,56 +50,110 @@ type IUser interface {
 type Claims struct {
 	UID       uint      `json:"uid"`
 	Name      string    `json:"name"`
-	TokenType TokenType `json:"token_type"` // access_token / refresh_token
+	TokenType TokenType `json:"token_type"`    // access_token / refresh_token
+	SessionID string    `json:"sid,omitempty"` // 所属登录会话
 	jwt.RegisteredClaims
 }
 
 // Config
 // -------------------- 配置 --------------------
 type Config struct {
-	Secret            string   `yaml:"secret" json:"secret,omitempty"`
-	Expiration        int      `yaml:"expiration" json:"expiration,omitempty"`                 // Access Token 过期秒数，0 表示永不过期
-	RefreshExpiration int      `yaml:"refresh_expiration" json:"refresh_expiration,omitempty"` // Refresh Token 过期秒数，0 表示永不过期
-	Issuer            string   `yaml:"issuer" json:"issuer,omitempty"`
-	Aud               []string `yaml:"aud" json:"aud,omitempty"`
+	Secret            string       `yaml:"secret" json:"secret,omitempty"`                         // HS256 共享密钥，不带 kid 的 token 使用该密钥
+	Keys              []*KeyConfig `yaml:"keys" json:"keys,omitempty"`                             // 签名密钥，轮换时保留旧密钥用于校验
+	ActiveKey         string       `yaml:"active_key" json:"active_key,omitempty"`                 // 签名使用的密钥 kid，为空时使用第一个可签名的密钥
+	Expiration        int          `yaml:"expiration" json:"expiration,omitempty"`                 // Access Token 过期秒数，0 表示永不过期
+	RefreshExpiration int          `yaml:"refresh_expiration" json:"refresh_expiration,omitempty"` // Refresh Token 过期秒数，0 表示永不过期
+	Issuer            string       `yaml:"issuer" json:"issuer,omitempty"`                         // 签发者，校验时要求 iss 一致
+	Aud               []string     `yaml:"aud" json:"aud,omitempty"`                               // 受众，校验时要求 aud 至少包含其中一个
+	Leeway            int          `yaml:"leeway" json:"leeway,omitempty"`                         // 校验 exp、nbf、iat 时允许的时钟偏差秒数
 }
 
 // JWTService
 // -------------------- JWT 接口 --------------------
 type JWTService interface {
-	BuildAccessToken(user IUser) (string, error)
-	BuildRefreshToken(user IUser) (string, error)
-	Parse(tokenStr string) (*Claims, error)
+	BuildMFAPendingToken(user IUser, expSec int) (string, error)
+	CreateSession(user IUser, ip, userAgent string) (accessToken string, refreshToken string, err error)
+	RevokeSession(sid string) error
+	RevokeUserSessions(uid uint) (int64, error)
+	Parse(tokenStr string, validators ...Validator) (*Claims, error)
 	Refresh(rToken string) (accessToken string, refreshToken string, err error)
 	GetAccessExpiresIn() int
 	GetRefreshExpiresIn() int
 	ExpiresIn(tokenStr string) (int, error)
+	JWKS() JWKS
+}
+
+// Validator 自定义 Claims 校验，在签名和标准字段校验通过后执行
+type Validator func(claims *Claims) error
+
+// RequireTokenType 要求 token 类型一致
+func RequireTokenType(tokenType TokenType) Validator {
+	return func(claims *Claims) error {
+		if claims.TokenType == tokenType {
+			return nil
+		}
+		if tokenType == RefreshTokenType {
+			return ErrNotRefreshToken
+		}
+		return ErrNotAccessToken
+	}
 }
 
 // -------------------- JWT 实现 --------------------
 type JWT struct {
-	config *Config
+	config     *Config
+	keys       *KeySet
+	store      SessionStore
+	parser     *jwt.Parser
+	validators []Validator
 }
 
-func NewJWT(cfg *Config) *JWT {
+func NewJWT(cfg *Config) (*JWT, error) {
 	if cfg.Issuer == "" {
 		cfg.Issuer = "skeleton"
 	}
 	if len(cfg.Aud) == 0 {
 		cfg.Aud = []string{"skeleton"}
 	}
-	return &JWT{config: cfg}
+	keys, err := NewKeySet(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// 校验签发者、受众，并允许一定的时钟偏差
+	parser := jwt.NewParser(
+		jwt.WithIssuer(cfg.Issuer),
+		jwt.WithAudience(cfg.Aud...),
+		jwt.WithLeeway(time.Duration(cfg.Leeway)*time.Second),
+		jwt.WithIssuedAt(),
+	)
+	return &JWT{config: cfg, keys: keys, parser: parser}, nil
+}
+
+// JWKS 返回用于校验 token 的公钥集合
+func (j *JWT) JWKS() JWKS {
+	return j.keys.JWKS()
+}
+
+// AddValidator 添加全局 Claims 校验，每次 Parse 都会执行
+func (j *JWT) AddValidator(validators ...Validator) {
+	j.validators = append(j.validators, validators...)
+}
+
+// SetSessionStore 设置会话存储，设置后只有有效会话签发的 token 才能通过校验
+func (j *JWT) SetSessionStore(store SessionStore) {
+	j.store = store
 }
 
 // 内部通用生成 token
-func (j *JWT) buildToken(uid uint, name string, tokenType TokenType, expSec int) (string, error) {
+func (j *JWT) buildToken(uid uint, name, sid, jti string, tokenType TokenType, expSec int) (string, error) {
 	now := time.Now()
 	claims := Claims{
 		UID:       uid,
 		Name:      name,
 		TokenType: tokenType,
+		SessionID: sid,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ID:        fmt.Sprintf("%d", uid),
+			ID:        jti,
 			Subject:   fmt.Sprintf("%d", uid),
 			Issuer:    j.config.Issuer,
 			Audience:  jwt.ClaimStrings(j.config.Aud),
@@ -103,28 +165,78 @@ func (j *JWT) buildToken(uid uint, name string, tokenType TokenType, expSec int)
 		claims.ExpiresAt = jwt.NewNumericDate(now.Add(time.Duration(expSec) * time.Second))
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.config.Secret))
+	key := j.keys.Active()
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.signKey)
 }
 
-// BuildAccessToken
 // -------------------- 对外方法 --------------------
-func (j *JWT) BuildAccessToken(user IUser) (string, error) {
-	return j.buildToken(user.GetID(), user.GetName(), AccessTokenType, j.config.Expiration)
+
+// BuildMFAPendingToken 签发等待两步验证的 token，不属于任何会话，只能用于提交验证码
+func (j *JWT) BuildMFAPendingToken(user IUser, expSec int) (string, error) {
+	return j.buildToken(user.GetID(), user.GetName(), "", uuid.NewString(), MFAPendingTokenType, expSec)
+}
+
+// CreateSession 创建登录会话并签发 access + refresh，会话有效期与 refresh token 一致
+//
+//	会话即 refresh token 的 token family，会话只记录当前有效的 refresh token
+func (j *JWT) CreateSession(user IUser, ip, userAgent string) (string, string, error) {
+	sid := uuid.NewString()
+	refreshID := uuid.NewString()
+	if j.store != nil {
+		session := &SysSession{ID: sid, UID: user.GetID(), IP: ip, UserAgent: userAgent, RefreshID: refreshID}
+		if j.config.RefreshExpiration > 0 {
+			expiresAt := time.Now().Add(time.Duration(j.config.RefreshExpiration) * time.Second)
+			session.ExpiresAt = &expiresAt
+		}
+		if err := j.store.Create(session); err != nil {
+			return "", "", err
+		}
+	}
+	access, err := j.buildToken(user.GetID(), user.GetName(), sid, uuid.NewString(), AccessTokenType, j.config.Expiration)
+	if err != nil {
+		return "", "", err
+	}
+	refresh, err := j.buildToken(user.GetID(), user.GetName(), sid, refreshID, RefreshTokenType, j.config.RefreshExpiration)
+	if err != nil {
+		return "", "", err
+	}
+	return access, refresh, nil
+}
+
+// RevokeSession 注销会话，会话下的所有 token 立即失效
+func (j *JWT) RevokeSession(sid string) error {
+	if j.store == nil {
+		return nil
+	}
+	return j.store.Revoke(sid)
+}
+
+// RevokeUserSessions 注销用户的所有会话
+func (j *JWT) RevokeUserSessions(uid uint) (int64, error) {
+	if j.store == nil {
+		return 0, nil
+	}
+	return j.store.RevokeUser(uid)
 }
 
-func (j *JWT) BuildRefreshToken(user IUser) (string, error) {
-	return j.buildToken(user.GetID(), user.GetName(), RefreshTokenType, j.config.RefreshExpiration)
+// keyFunc 按 kid 查找校验密钥，算法必须与密钥一致
+func (j *JWT) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := j.keys.Lookup(kid)
+	if !ok || token.Method.Alg() != key.Method.Alg() {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	return key.verifyKey, nil
 }
 
-func (j *JWT) Parse(tokenStr string) (*Claims, error) {
+// Parse 解析并校验 token，validators 为本次额外执行的校验
+func (j *JWT) Parse(tokenStr string, validators ...Validator) (*Claims, error) {
 	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-		if token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
-			return nil, jwt.ErrSignatureInvalid
-		}
-		return []byte(j.config.Secret), nil
-	})
+	token, err := j.parser.ParseWithClaims(tokenStr, claims, j.keyFunc)
 	if err != nil {
 		switch {
 		case errors.Is(err, jwt.ErrTokenExpired):
@@ -133,8 +245,12 @@ func (j *JWT) Parse(tokenStr string) (*Claims, error) {
 			return nil, TokenMalformed
 		case errors.Is(err, jwt.ErrTokenSignatureInvalid):
 			return nil, TokenSignatureInvalid
-		case errors.Is(err, jwt.ErrTokenNotValidYet):
+		case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
 			return nil, TokenNotValidYet
+		case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+			return nil, TokenInvalidIssuer
+		case errors.Is(err, jwt.ErrTokenInvalidAudience):
+			return nil, TokenInvalidAudience
 		default:
 			return nil, TokenInvalid
 		}
@@ -142,24 +258,63 @@ func (j *JWT) Parse(tokenStr string) (*Claims, error) {
 	if !token.Valid {
 		return nil, TokenValid
 	}
+	for _, list := range [][]Validator{j.validators, validators} {
+		for _, validate := range list {
+			if err := validate(claims); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if j.store != nil && claims.TokenType != MFAPendingTokenType {
+		if claims.SessionID == "" {
+			return nil, ErrSessionRevoked
+		}
+		active, err := j.store.Active(claims.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			return nil, ErrSessionRevoked
+		}
+	}
 	return claims, nil
 }
 
 // Refresh 用 refresh token 续签新的 access + refresh
+//
+//	设置会话存储时 refresh token 只能使用一次，重复使用视为被盗用，注销整个会话
 func (j *JWT) Refresh(refreshToken string) (string, string, error) {
-	claims, err := j.Parse(refreshToken)
+	claims, err := j.Parse(refreshToken, RequireTokenType(RefreshTokenType))
 	if err != nil {
 		return "", "", err
 	}
-	if claims.TokenType != RefreshTokenType {
-		return "", "", ErrNotRefreshToken
-	}
 
-	newAccess, err := j.buildToken(claims.UID, claims.Name, AccessTokenType, j.config.Expiration)
+	refreshID := uuid.NewString()
+	if j.store != nil {
+		// 轮换 refresh token，会话有效期随之顺延
+		var expiresAt *time.Time
+		if j.config.RefreshExpiration > 0 {
+			t := time.Now().Add(time.Duration(j.config.RefreshExpiration) * time.Second)
+			expiresAt = &t
+		}
+		rotated, err := j.store.Rotate(claims.SessionID, claims.ID, refreshID, expiresAt)
+		if err != nil {
+			return "", "", err
+		}
+		if !rotated {
+			slog.Warn("[JWT] refresh token reuse detected, session revoked",
+				slog.String("sid", claims.SessionID), slog.Uint64("uid", uint64(claims.UID)), slog.String("jti", claims.ID))
+			if err := j.store.Revoke(claims.SessionID); err != nil {
+				return "", "", err
+			}
+			return "", "", ErrRefreshTokenReused
+		}
+	}
+	newAccess, err := j.buildToken(claims.UID, claims.Name, claims.SessionID, uuid.NewString(), AccessTokenType, j.config.Expiration)
 	if err != nil {
 		return "", "", err
 	}
-	newRefresh, err := j.buildToken(claims.UID, claims.Name, RefreshTokenType, j.config.RefreshExpiration)
+	newRefresh, err := j.buildToken(claims.UID, claims.Name, claims.SessionID, refreshID, RefreshTokenType, j.config.RefreshExpiration)
 	if err != nil {
 		return "", "", err
 	}
@@ -1,9 +1,11 @@
 package jwts
 
 import (
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,16 +29,13 @@ func TestJWT_Basic(t *testing.T) {
 		Aud:               []string{"test-aud"},
 	}
 
-	j := NewJWT(jwtCfg)
-
-	// 生成 access token
-	accessToken, err := j.BuildAccessToken(user)
+	j, err := NewJWT(jwtCfg)
 	assert.NoError(t, err)
-	assert.NotEmpty(t, accessToken)
 
-	// 生成 refresh token
-	refreshToken, err := j.BuildRefreshToken(user)
+	// 生成 access token 和 refresh token
+	accessToken, refreshToken, err := j.CreateSession(user, "", "")
 	assert.NoError(t, err)
+	assert.NotEmpty(t, accessToken)
 	assert.NotEmpty(t, refreshToken)
 
 	// 解析 access token
@@ -65,10 +64,11 @@ func TestJWT_Refresh(t *testing.T) {
 		Aud:               []string{"test-aud"},
 	}
 
-	j := NewJWT(jwtCfg)
+	j, err := NewJWT(jwtCfg)
+	assert.NoError(t, err)
 
 	// 构造 refresh token
-	refreshToken, err := j.BuildRefreshToken(user)
+	_, refreshToken, err := j.CreateSession(user, "", "")
 	assert.NoError(t, err)
 	assert.NotEmpty(t, refreshToken)
 
@@ -101,10 +101,10 @@ func TestJWT_ExpiresIn(t *testing.T) {
 		Aud:               []string{"test-aud"},
 	}
 
-	j := NewJWT(jwtCfg)
+	j, err := NewJWT(jwtCfg)
+	assert.NoError(t, err)
 
-	accessToken, _ := j.BuildAccessToken(user)
-	refreshToken, _ := j.BuildRefreshToken(user)
+	accessToken, refreshToken, _ := j.CreateSession(user, "", "")
 
 	// Access token 剩余时间
 	sec, err := j.ExpiresIn(accessToken)
@@ -121,3 +121,63 @@ func TestJWT_ExpiresIn(t *testing.T) {
 	_, err = j.Parse(accessToken)
 	assert.Equal(t, TokenExpired, err)
 }
+
+func TestJWT_Validate(t *testing.T) {
+	user := &mockUser{id: 123, name: "Alice"}
+	newJWT := func(issuer string, aud ...string) *JWT {
+		j, err := NewJWT(&Config{Secret: "test-secret", Expiration: 3600, Issuer: issuer, Aud: aud, Leeway: 5})
+		assert.NoError(t, err)
+		return j
+	}
+	j := newJWT("test-issuer", "admin", "api")
+	token, refreshToken, err := j.CreateSession(user, "", "")
+	assert.NoError(t, err)
+
+	// 共享密钥的其他服务签发的 token
+	_, err = newJWT("test-issuer", "other").Parse(token)
+	assert.Equal(t, TokenInvalidAudience, err)
+	_, err = newJWT("other-issuer", "admin").Parse(token)
+	assert.Equal(t, TokenInvalidIssuer, err)
+	_, err = newJWT("test-issuer", "api").Parse(token)
+	assert.NoError(t, err)
+
+	// token 类型校验
+	_, err = j.Parse(refreshToken, RequireTokenType(AccessTokenType))
+	assert.Equal(t, ErrNotAccessToken, err)
+	_, _, err = j.Refresh(token)
+	assert.Equal(t, ErrNotRefreshToken, err)
+
+	// 全局校验
+	errDisabled := errors.New("disabled")
+	j.AddValidator(func(claims *Claims) error {
+		if claims.UID == user.id {
+			return errDisabled
+		}
+		return nil
+	})
+	_, err = j.Parse(token)
+	assert.Equal(t, errDisabled, err)
+}
+
+func TestJWT_Leeway(t *testing.T) {
+	j, err := NewJWT(&Config{Secret: "test-secret", Issuer: "test-issuer", Aud: []string{"test-aud"}, Leeway: 5})
+	assert.NoError(t, err)
+	sign := func(exp time.Time) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+			UID:       1,
+			TokenType: AccessTokenType,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    "test-issuer",
+				Audience:  jwt.ClaimStrings{"test-aud"},
+				ExpiresAt: jwt.NewNumericDate(exp),
+			},
+		}).SignedString([]byte("test-secret"))
+		assert.NoError(t, err)
+		return token
+	}
+
+	_, err = j.Parse(sign(time.Now().Add(-2 * time.Second)))
+	assert.NoError(t, err)
+	_, err = j.Parse(sign(time.Now().Add(-10 * time.Second)))
+	assert.Equal(t, TokenExpired, err)
+}
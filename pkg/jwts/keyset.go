@@ -0,0 +1,269 @@
+package jwts
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgHS256 = "HS256" // HMAC + SHA256，共享密钥，默认
+	AlgRS256 = "RS256" // RSA + SHA256
+	AlgES256 = "ES256" // ECDSA P-256 + SHA256
+	AlgEdDSA = "EdDSA" // Ed25519
+)
+
+// KeyConfig 签名密钥配置
+type KeyConfig struct {
+	Kid        string `yaml:"kid" json:"kid,omitempty"`                 // 密钥 ID，写入 token 头部的 kid
+	Algorithm  string `yaml:"algorithm" json:"algorithm,omitempty"`     // 签名算法：HS256、RS256、ES256、EdDSA，为空时根据密钥类型推断
+	Secret     string `yaml:"secret" json:"secret,omitempty"`           // HS256 共享密钥
+	PrivateKey string `yaml:"private_key" json:"private_key,omitempty"` // 私钥 PEM 文件路径，用于签名
+	PublicKey  string `yaml:"public_key" json:"public_key,omitempty"`   // 公钥 PEM 文件路径，只校验不签名的旧密钥只需配置公钥
+}
+
+// Key 签名密钥
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   any // 私钥或共享密钥，为空表示只能校验
+	verifyKey any // 公钥或共享密钥
+}
+
+// KeySet 密钥集合，使用当前密钥签名，按 kid 查找密钥校验
+type KeySet struct {
+	keys   []*Key
+	active *Key
+}
+
+// NewKeySet 根据配置加载密钥
+//
+//	配置了 Secret 时作为不带 kid 的 HS256 密钥，用于兼容未配置 Keys 时签发的 token
+func NewKeySet(cfg *Config) (*KeySet, error) {
+	set := &KeySet{}
+	for _, kc := range cfg.Keys {
+		key, err := loadKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", kc.Kid, err)
+		}
+		if _, ok := set.Lookup(key.ID); ok {
+			return nil, fmt.Errorf("jwt key %q: duplicate kid", key.ID)
+		}
+		set.keys = append(set.keys, key)
+	}
+	if cfg.Secret != "" {
+		set.keys = append(set.keys, &Key{ID: "", Method: jwt.SigningMethodHS256, signKey: []byte(cfg.Secret), verifyKey: []byte(cfg.Secret)})
+	}
+	if len(set.keys) == 0 {
+		return nil, errors.New("jwt: secret or keys is required")
+	}
+	// 未指定当前密钥时使用第一个可签名的密钥
+	for _, key := range set.keys {
+		if (cfg.ActiveKey == "" || key.ID == cfg.ActiveKey) && key.signKey != nil {
+			set.active = key
+			break
+		}
+	}
+	if set.active == nil {
+		return nil, fmt.Errorf("jwt: active key %q not found or has no private key", cfg.ActiveKey)
+	}
+	return set, nil
+}
+
+// Active 返回当前签名密钥
+func (s *KeySet) Active() *Key {
+	return s.active
+}
+
+// Lookup 按 kid 查找密钥
+func (s *KeySet) Lookup(kid string) (*Key, bool) {
+	for _, key := range s.keys {
+		if key.ID == kid {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// JWK 单个公钥，见 RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS 公钥集合
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 返回所有非对称密钥的公钥，共享密钥不会公开
+func (s *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range s.keys {
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+		enc := base64.RawURLEncoding
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = enc.EncodeToString(pub.N.Bytes())
+			jwk.E = enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = pub.Curve.Params().Name
+			jwk.X = enc.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+			jwk.Y = enc.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = enc.EncodeToString(pub)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+// loadKey 加载单个密钥并校验算法与密钥类型是否匹配
+func loadKey(kc *KeyConfig) (*Key, error) {
+	if kc.Kid == "" {
+		return nil, errors.New("kid is required")
+	}
+	key := &Key{ID: kc.Kid}
+	switch {
+	case kc.Secret != "":
+		key.signKey, key.verifyKey = []byte(kc.Secret), []byte(kc.Secret)
+	case kc.PrivateKey != "":
+		data, err := os.ReadFile(kc.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		priv, err := parsePrivateKey(data)
+		if err != nil {
+			return nil, err
+		}
+		key.signKey, key.verifyKey = priv, priv.Public()
+	case kc.PublicKey != "":
+		data, err := os.ReadFile(kc.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		if key.verifyKey, err = parsePublicKey(data); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("secret, private_key or public_key is required")
+	}
+
+	alg := kc.Algorithm
+	if alg == "" {
+		alg = inferAlgorithm(key.verifyKey)
+	}
+	if alg == "" || inferAlgorithm(key.verifyKey) != alg {
+		return nil, fmt.Errorf("algorithm %q does not match the key", kc.Algorithm)
+	}
+	key.Method = jwt.GetSigningMethod(alg)
+	return key, nil
+}
+
+// inferAlgorithm 根据密钥类型推断签名算法
+func inferAlgorithm(key any) string {
+	switch k := key.(type) {
+	case []byte:
+		return AlgHS256
+	case *rsa.PublicKey:
+		return AlgRS256
+	case *ecdsa.PublicKey:
+		if k.Curve == elliptic.P256() {
+			return AlgES256
+		}
+	case ed25519.PublicKey:
+		return AlgEdDSA
+	}
+	return ""
+}
+
+// parsePrivateKey 解析 PKCS#8、PKCS#1 或 SEC1 格式的私钥
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid private key PEM")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, errors.New("unsupported private key type")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("unsupported private key format")
+}
+
+// parsePublicKey 解析 PKIX 或 PKCS#1 格式的公钥
+func parsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid public key PEM")
+	}
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("unsupported public key format")
+}
+
+// GenerateKey 生成非对称密钥对，返回 PKCS#8 私钥和 PKIX 公钥的 PEM
+func GenerateKey(alg string) (privatePEM, publicPEM []byte, err error) {
+	var priv crypto.Signer
+	switch alg {
+	case AlgRS256:
+		priv, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgES256:
+		priv, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, priv, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, nil, fmt.Errorf("unsupported algorithm: %s", alg)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(priv.Public())
+	if err != nil {
+		return nil, nil, err
+	}
+	privatePEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER})
+	publicPEM = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+	return privatePEM, publicPEM, nil
+}
@@ -0,0 +1,89 @@
+package jwts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKey 生成密钥对并写入临时目录，返回私钥和公钥路径
+func writeKey(t *testing.T, alg, kid string) (string, string) {
+	priv, pub, err := GenerateKey(alg)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	privPath := filepath.Join(dir, kid+".pem")
+	pubPath := filepath.Join(dir, kid+".pub.pem")
+	require.NoError(t, os.WriteFile(privPath, priv, 0600))
+	require.NoError(t, os.WriteFile(pubPath, pub, 0644))
+	return privPath, pubPath
+}
+
+func TestKeySet_Algorithms(t *testing.T) {
+	user := &mockUser{id: 1, name: "Alice"}
+	for _, alg := range []string{AlgRS256, AlgES256, AlgEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			priv, pub := writeKey(t, alg, "k1")
+			j, err := NewJWT(&Config{Keys: []*KeyConfig{{Kid: "k1", PrivateKey: priv}}, Expiration: 3600})
+			require.NoError(t, err)
+			token, _, err := j.CreateSession(user, "", "")
+			require.NoError(t, err)
+			claims, err := j.Parse(token)
+			require.NoError(t, err)
+			assert.Equal(t, user.id, claims.UID)
+
+			// 只有公钥时没有可签名的密钥
+			_, err = NewJWT(&Config{Keys: []*KeyConfig{{Kid: "k1", Algorithm: alg, PublicKey: pub}}})
+			assert.Error(t, err)
+
+			jwks := j.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, "k1", jwks.Keys[0].Kid)
+			assert.Equal(t, alg, jwks.Keys[0].Alg)
+		})
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	user := &mockUser{id: 1, name: "Alice"}
+	oldPriv, oldPub := writeKey(t, AlgRS256, "old")
+	newPriv, _ := writeKey(t, AlgES256, "new")
+
+	legacy, err := NewJWT(&Config{Secret: "test-secret", Expiration: 3600})
+	require.NoError(t, err)
+	legacyToken, _, err := legacy.CreateSession(user, "", "")
+	require.NoError(t, err)
+
+	before, err := NewJWT(&Config{Secret: "test-secret", Keys: []*KeyConfig{{Kid: "old", PrivateKey: oldPriv}}, Expiration: 3600})
+	require.NoError(t, err)
+	oldToken, _, err := before.CreateSession(user, "", "")
+	require.NoError(t, err)
+
+	// 轮换后新密钥签名，旧密钥只保留公钥用于校验
+	after, err := NewJWT(&Config{
+		Secret:    "test-secret",
+		ActiveKey: "new",
+		Keys: []*KeyConfig{
+			{Kid: "old", PublicKey: oldPub},
+			{Kid: "new", PrivateKey: newPriv},
+		},
+		Expiration: 3600,
+	})
+	require.NoError(t, err)
+	newToken, _, err := after.CreateSession(user, "", "")
+	require.NoError(t, err)
+
+	for _, token := range []string{legacyToken, oldToken, newToken} {
+		_, err := after.Parse(token)
+		assert.NoError(t, err)
+	}
+	_, err = before.Parse(newToken)
+	assert.ErrorIs(t, err, TokenSignatureInvalid)
+	assert.Len(t, after.JWKS().Keys, 2)
+
+	// 算法与密钥不匹配
+	_, err = NewJWT(&Config{Keys: []*KeyConfig{{Kid: "old", Algorithm: AlgES256, PrivateKey: oldPriv}}})
+	assert.Error(t, err)
+}
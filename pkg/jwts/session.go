@@ -0,0 +1,193 @@
+package jwts
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSessionRevoked = errors.New("登录已失效，请重新登录")
+)
+
+const (
+	sessionCachePrefix = "jwt:session:" // 会话状态缓存 key 前缀
+	sessionCacheTTL    = time.Minute    // 缓存失效失败时，其他实例最多在该时间内继续使用旧状态
+)
+
+// SysSession 登录会话，同一次登录签发的 access token 和 refresh token 共享一个会话
+//
+//	会话即 refresh token 的 token family，RefreshID 为当前唯一可用的 refresh token 的 jti
+type SysSession struct {
+	ID        string     `gorm:"primaryKey;size:36" json:"id"`
+	UID       uint       `gorm:"index" json:"uid"`
+	RefreshID string     `gorm:"size:36" json:"-"`
+	IP        string     `gorm:"size:64" json:"ip"`
+	UserAgent string     `gorm:"size:255" json:"user_agent"`
+	ExpiresAt *time.Time `gorm:"index" json:"expires_at"` // 会话过期时间，为空表示不过期
+	RevokedAt *time.Time `gorm:"index" json:"revoked_at"` // 注销时间，不为空表示已注销
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Active 会话是否有效
+func (s *SysSession) Active() bool {
+	return s.RevokedAt == nil && (s.ExpiresAt == nil || s.ExpiresAt.After(time.Now()))
+}
+
+// SessionStore 会话存储
+type SessionStore interface {
+	// Create 保存新会话
+	Create(session *SysSession) error
+	// Active 会话是否有效
+	Active(sid string) (bool, error)
+	// Rotate 轮换 refresh token 并更新会话有效期，oldID 不是当前 refresh token 时返回 false
+	Rotate(sid, oldID, newID string, expiresAt *time.Time) (bool, error)
+	// Revoke 注销会话
+	Revoke(sid string) error
+	// RevokeUser 注销用户的所有会话，返回注销的数量
+	RevokeUser(uid uint) (int64, error)
+}
+
+// DBSessionStore 数据库会话存储，配置 Redis 时缓存会话状态
+type DBSessionStore struct {
+	db    *gorm.DB
+	rdb   redis.UniversalClient
+	stale sync.Map // 缓存失效失败的会话 sid -> 旧缓存过期时间，期间绕过缓存直接查库
+}
+
+// NewSessionStore 创建会话存储，rdb 为空时不使用缓存
+func NewSessionStore(db *gorm.DB, rdb redis.UniversalClient) *DBSessionStore {
+	_ = db.AutoMigrate(&SysSession{})
+	return &DBSessionStore{db: db, rdb: rdb}
+}
+
+func (s *DBSessionStore) Create(session *SysSession) error {
+	return s.db.Create(session).Error
+}
+
+func (s *DBSessionStore) Active(sid string) (bool, error) {
+	ctx := context.Background()
+	stale := s.isStale(sid)
+	if s.rdb != nil && !stale {
+		if val, err := s.rdb.Get(ctx, sessionCachePrefix+sid).Result(); err == nil {
+			return val == "1", nil
+		}
+	}
+	var session SysSession
+	if err := s.db.Where("id = ?", sid).Take(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	active := session.Active()
+	if s.rdb != nil {
+		ttl := sessionCacheTTL
+		if active && session.ExpiresAt != nil {
+			ttl = min(ttl, time.Until(*session.ExpiresAt))
+		}
+		var err error
+		if active {
+			// 只在没有缓存时写入，查库后并发注销写入的 "0" 不会被覆盖
+			err = s.rdb.SetNX(ctx, sessionCachePrefix+sid, "1", ttl).Err()
+		} else {
+			err = s.rdb.Set(ctx, sessionCachePrefix+sid, "0", ttl).Err()
+		}
+		if err == nil && stale {
+			// 缓存已修正
+			s.stale.Delete(sid)
+		}
+	}
+	return active, nil
+}
+
+func (s *DBSessionStore) Rotate(sid, oldID, newID string, expiresAt *time.Time) (bool, error) {
+	// 条件更新保证并发使用同一个 refresh token 时只有一个成功
+	result := s.db.Model(&SysSession{}).
+		Where("id = ? AND refresh_id = ? AND revoked_at IS NULL", sid, oldID).
+		Updates(map[string]any{"refresh_id": newID, "expires_at": expiresAt})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (s *DBSessionStore) Revoke(sid string) error {
+	if err := s.db.Model(&SysSession{}).
+		Where("id = ? AND revoked_at IS NULL", sid).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	s.uncache(sid)
+	return nil
+}
+
+func (s *DBSessionStore) RevokeUser(uid uint) (int64, error) {
+	var sids []string
+	if err := s.db.Model(&SysSession{}).
+		Where("uid = ? AND revoked_at IS NULL", uid).
+		Pluck("id", &sids).Error; err != nil {
+		return 0, err
+	}
+	if len(sids) == 0 {
+		return 0, nil
+	}
+	result := s.db.Model(&SysSession{}).
+		Where("id IN ? AND revoked_at IS NULL", sids).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	s.uncache(sids...)
+	return result.RowsAffected, nil
+}
+
+// uncache 将会话缓存标记为已注销，避免其他实例继续使用旧缓存
+//
+//	注销已写入数据库，缓存失败不影响注销结果，记录日志并在本实例绕过缓存直到旧缓存过期
+func (s *DBSessionStore) uncache(sids ...string) {
+	if s.rdb == nil || len(sids) == 0 {
+		return
+	}
+	ctx := context.Background()
+	pipe := s.rdb.Pipeline()
+	for _, sid := range sids {
+		pipe.Set(ctx, sessionCachePrefix+sid, "0", sessionCacheTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		slog.Warn("[JWT] session cache invalidation failed, revoked sessions may stay active until cache expires",
+			slog.Any("err", err), slog.Any("sids", sids), slog.Duration("ttl", sessionCacheTTL))
+		s.markStale(sids)
+	}
+}
+
+// markStale 记录缓存失效失败的会话，顺带清理已过期的记录
+func (s *DBSessionStore) markStale(sids []string) {
+	now := time.Now()
+	s.stale.Range(func(key, value any) bool {
+		if now.After(value.(time.Time)) {
+			s.stale.Delete(key)
+		}
+		return true
+	})
+	for _, sid := range sids {
+		s.stale.Store(sid, now.Add(sessionCacheTTL))
+	}
+}
+
+// isStale 会话缓存是否可能仍为注销前的旧状态
+func (s *DBSessionStore) isStale(sid string) bool {
+	v, ok := s.stale.Load(sid)
+	if !ok {
+		return false
+	}
+	if time.Now().After(v.(time.Time)) {
+		s.stale.Delete(sid)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,192 @@
+package jwts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newSessionJWT(t *testing.T, rdb redis.UniversalClient) *JWT {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	j, err := NewJWT(&Config{Secret: "test-secret", Expiration: 3600, RefreshExpiration: 7200})
+	require.NoError(t, err)
+	j.SetSessionStore(NewSessionStore(db, rdb))
+	return j
+}
+
+func testSession(t *testing.T, j *JWT) {
+	user := &mockUser{id: 1, name: "Alice"}
+	access, refresh, err := j.CreateSession(user, "127.0.0.1", "test")
+	require.NoError(t, err)
+
+	claims, err := j.Parse(access)
+	require.NoError(t, err)
+	assert.NotEmpty(t, claims.SessionID)
+	assert.NotEmpty(t, claims.ID)
+
+	// 续签保留会话
+	newAccess, _, err := j.Refresh(refresh)
+	require.NoError(t, err)
+	refreshed, err := j.Parse(newAccess)
+	require.NoError(t, err)
+	assert.Equal(t, claims.SessionID, refreshed.SessionID)
+	assert.NotEqual(t, claims.ID, refreshed.ID)
+
+	// 注销后 access token 和 refresh token 都失效
+	require.NoError(t, j.RevokeSession(claims.SessionID))
+	_, err = j.Parse(access)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+	_, _, err = j.Refresh(refresh)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+
+	// 注销用户的所有会话
+	a1, _, err := j.CreateSession(user, "", "")
+	require.NoError(t, err)
+	a2, _, err := j.CreateSession(user, "", "")
+	require.NoError(t, err)
+	other, _, err := j.CreateSession(&mockUser{id: 2, name: "Bob"}, "", "")
+	require.NoError(t, err)
+	_, err = j.Parse(a1)
+	require.NoError(t, err)
+	n, err := j.RevokeUserSessions(user.id)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	_, err = j.Parse(a1)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+	_, err = j.Parse(a2)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+	_, err = j.Parse(other)
+	assert.NoError(t, err)
+
+	// 启用会话后不接受没有会话的 token
+	token, err := j.buildToken(user.id, user.name, "", "jti", AccessTokenType, 3600)
+	require.NoError(t, err)
+	_, err = j.Parse(token)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+}
+
+func TestSession_DB(t *testing.T) {
+	testSession(t, newSessionJWT(t, nil))
+}
+
+func TestSession_Cache(t *testing.T) {
+	mr := miniredis.RunT(t)
+	testSession(t, newSessionJWT(t, redis.NewClient(&redis.Options{Addr: mr.Addr()})))
+}
+
+func TestSession_RefreshReuse(t *testing.T) {
+	j := newSessionJWT(t, nil)
+	user := &mockUser{id: 1, name: "Alice"}
+	access, refresh, err := j.CreateSession(user, "", "")
+	require.NoError(t, err)
+
+	newAccess, newRefresh, err := j.Refresh(refresh)
+	require.NoError(t, err)
+	_, err = j.Parse(newAccess)
+	require.NoError(t, err)
+
+	// 重复使用旧的 refresh token，整个会话被注销
+	_, _, err = j.Refresh(refresh)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	_, _, err = j.Refresh(newRefresh)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+	_, err = j.Parse(access)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+	_, err = j.Parse(newAccess)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+}
+
+// failPipeline 模拟 Redis 写入失败，读取仍然正常
+type failPipeline struct {
+	fail bool
+}
+
+func (h *failPipeline) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *failPipeline) ProcessHook(next redis.ProcessHook) redis.ProcessHook { return next }
+
+func (h *failPipeline) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if h.fail {
+			return errors.New("pipeline failed")
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func TestSession_UncacheFailed(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	hook := &failPipeline{}
+	rdb.AddHook(hook)
+	j := newSessionJWT(t, rdb)
+
+	access, _, err := j.CreateSession(&mockUser{id: 1, name: "Alice"}, "", "")
+	require.NoError(t, err)
+	claims, err := j.Parse(access)
+	require.NoError(t, err)
+	mr.CheckGet(t, sessionCachePrefix+claims.SessionID, "1")
+
+	// 缓存失效失败时绕过仍为有效状态的旧缓存
+	hook.fail = true
+	require.NoError(t, j.RevokeSession(claims.SessionID))
+	_, err = j.Parse(access)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+
+	// 查库后修正缓存
+	mr.CheckGet(t, sessionCachePrefix+claims.SessionID, "0")
+}
+
+// beforeSet 在第一次写缓存前执行 fn，模拟查库和回填之间发生的并发操作
+type beforeSet struct {
+	fn func()
+}
+
+func (h *beforeSet) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *beforeSet) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if h.fn != nil && cmd.Name() == "set" {
+			h.fn()
+			h.fn = nil
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h *beforeSet) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestSession_FillAfterRevoke(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	hook := &beforeSet{}
+	rdb.AddHook(hook)
+	j := newSessionJWT(t, rdb)
+
+	access, _, err := j.CreateSession(&mockUser{id: 1, name: "Alice"}, "", "")
+	require.NoError(t, err)
+	claims, err := j.Parse(access)
+	require.NoError(t, err)
+	key := sessionCachePrefix + claims.SessionID
+
+	// 查库时会话有效，回填前会话被注销，回填不能覆盖注销结果
+	mr.Del(key)
+	hook.fn = func() { require.NoError(t, mr.Set(key, "0")) }
+	_, err = j.Parse(access)
+	require.NoError(t, err)
+	mr.CheckGet(t, key, "0")
+	_, err = j.Parse(access)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+}
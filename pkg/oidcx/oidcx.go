@@ -0,0 +1,171 @@
+package oidcx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrProviderNotFound = errors.New("未配置的登录方式")
+	ErrEmailNotVerified = errors.New("身份提供方未验证该邮箱")
+)
+
+// Config 单个 OIDC 身份提供方配置
+type Config struct {
+	Issuer       string            `yaml:"issuer" json:"issuer,omitempty"`               // 身份提供方地址，通过 /.well-known/openid-configuration 发现端点
+	ClientID     string            `yaml:"client_id" json:"client_id,omitempty"`         // 客户端 ID
+	ClientSecret string            `yaml:"client_secret" json:"client_secret,omitempty"` // 客户端密钥，公共客户端可为空
+	RedirectURL  string            `yaml:"redirect_url" json:"redirect_url,omitempty"`   // 回调地址，例如 http://127.0.0.1:8080/api/admin/oauth/company/callback
+	Scopes       []string          `yaml:"scopes" json:"scopes,omitempty"`               // 申请的 scope，默认 openid email profile
+	GroupsClaim  string            `yaml:"groups_claim" json:"groups_claim,omitempty"`   // ID token 中用户组字段，默认 groups
+	Provision    bool              `yaml:"provision" json:"provision,omitempty"`         // 用户不存在时自动创建
+	TrustEmail   bool              `yaml:"trust_email" json:"trust_email,omitempty"`     // ID token 没有 email_verified 时仍信任邮箱，仅用于确认只签发已验证邮箱的身份提供方
+	RoleMapping  map[string]string `yaml:"role_mapping" json:"role_mapping,omitempty"`   // 用户组到角色编码的映射，配置后每次登录按用户组同步角色
+}
+
+// Identity 身份提供方返回的用户信息
+type Identity struct {
+	Issuer  string
+	Subject string
+	Email   string
+	Name    string
+	Groups  []string
+}
+
+// Provider OIDC 身份提供方
+type Provider struct {
+	Name     string
+	config   *Config
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewProvider 通过发现文档初始化身份提供方
+func NewProvider(ctx context.Context, name string, cfg *Config) (*Provider, error) {
+	op, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oidc provider %s: %w", name, err)
+	}
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+	return &Provider{
+		Name:   name,
+		config: cfg,
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     op.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier: op.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}, nil
+}
+
+// Config 返回身份提供方配置
+func (p *Provider) Config() *Config {
+	return p.config
+}
+
+// AuthURL 返回授权地址，使用 PKCE S256，verifier 和 nonce 需保存到回调时校验
+func (p *Provider) AuthURL(state, verifier, nonce string) string {
+	return p.oauth2.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier), oidc.Nonce(nonce))
+}
+
+// Exchange 使用授权码换取 token，校验 ID token 的签名、签发者、受众、有效期和 nonce
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, err
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("oidc: token response has no id_token")
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("oidc: nonce mismatch")
+	}
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+	identity := &Identity{Issuer: idToken.Issuer, Subject: idToken.Subject}
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	// 邮箱用于关联本地账号，缺少 email_verified 时默认不信任
+	if verified, ok := claims["email_verified"].(bool); (ok && !verified) || (!ok && !p.config.TrustEmail) {
+		return nil, ErrEmailNotVerified
+	}
+	if identity.Email == "" {
+		return nil, errors.New("oidc: id_token has no email")
+	}
+	groupsClaim := p.config.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	if groups, ok := claims[groupsClaim].([]any); ok {
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				identity.Groups = append(identity.Groups, s)
+			}
+		}
+	}
+	return identity, nil
+}
+
+// Roles 按映射返回用户组对应的角色编码，未配置映射时返回 false
+func (p *Provider) Roles(groups []string) ([]string, bool) {
+	if len(p.config.RoleMapping) == 0 {
+		return nil, false
+	}
+	var codes []string
+	for _, g := range groups {
+		if code, ok := p.config.RoleMapping[g]; ok {
+			codes = append(codes, code)
+		}
+	}
+	return codes, true
+}
+
+// Providers 身份提供方集合，首次使用时才访问发现文档，避免身份提供方不可用时影响启动
+type Providers struct {
+	configs   map[string]*Config
+	lock      sync.Mutex
+	providers map[string]*Provider
+}
+
+// NewProviders 创建身份提供方集合
+func NewProviders(configs map[string]*Config) *Providers {
+	return &Providers{configs: configs, providers: make(map[string]*Provider)}
+}
+
+// Get 获取身份提供方
+func (ps *Providers) Get(ctx context.Context, name string) (*Provider, error) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	if p, ok := ps.providers[name]; ok {
+		return p, nil
+	}
+	cfg, ok := ps.configs[name]
+	if !ok {
+		return nil, ErrProviderNotFound
+	}
+	// 公钥集合会在之后的请求中继续使用该 context 拉取公钥，不能随请求取消
+	p, err := NewProvider(context.WithoutCancel(ctx), name, cfg)
+	if err != nil {
+		return nil, err
+	}
+	ps.providers[name] = p
+	return p, nil
+}
@@ -0,0 +1,87 @@
+package oidcx
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"wangzhiqiang/skeleton/pkg/oidcx/oidcxtest"
+)
+
+// authorize 模拟浏览器访问授权地址，返回回调中的授权码
+func authorize(t *testing.T, authURL, state string) string {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, state, location.Query().Get("state"))
+	return location.Query().Get("code")
+}
+
+func TestProvider_Exchange(t *testing.T) {
+	idp := oidcxtest.NewServer()
+	defer idp.Close()
+	idp.Claims["groups"] = []string{"admins", "staff"}
+
+	ctx := context.Background()
+	providers := NewProviders(map[string]*Config{"mock": {
+		Issuer:       idp.Issuer(),
+		ClientID:     oidcxtest.ClientID,
+		ClientSecret: oidcxtest.ClientSecret,
+		RedirectURL:  "http://127.0.0.1/api/admin/oauth/mock/callback",
+		RoleMapping:  map[string]string{"admins": "admin"},
+	}})
+	_, err := providers.Get(ctx, "other")
+	assert.ErrorIs(t, err, ErrProviderNotFound)
+	p, err := providers.Get(ctx, "mock")
+	require.NoError(t, err)
+
+	verifier := oauth2.GenerateVerifier()
+	code := authorize(t, p.AuthURL("state-1", verifier, "nonce-1"), "state-1")
+	identity, err := p.Exchange(ctx, code, verifier, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, idp.Issuer(), identity.Issuer)
+	assert.Equal(t, "mock-user", identity.Subject)
+	assert.Equal(t, "sso@example.com", identity.Email)
+	assert.Equal(t, []string{"admins", "staff"}, identity.Groups)
+	roles, ok := p.Roles(identity.Groups)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"admin"}, roles)
+
+	// 授权码只能使用一次
+	_, err = p.Exchange(ctx, code, verifier, "nonce-1")
+	assert.Error(t, err)
+
+	// PKCE verifier 不匹配
+	code = authorize(t, p.AuthURL("state-2", verifier, "nonce-2"), "state-2")
+	_, err = p.Exchange(ctx, code, oauth2.GenerateVerifier(), "nonce-2")
+	assert.Error(t, err)
+
+	// nonce 不匹配
+	code = authorize(t, p.AuthURL("state-3", verifier, "nonce-3"), "state-3")
+	_, err = p.Exchange(ctx, code, verifier, "other")
+	assert.Error(t, err)
+
+	// 邮箱未验证
+	idp.Claims["email_verified"] = false
+	code = authorize(t, p.AuthURL("state-4", verifier, "nonce-4"), "state-4")
+	_, err = p.Exchange(ctx, code, verifier, "nonce-4")
+	assert.ErrorIs(t, err, ErrEmailNotVerified)
+
+	// 缺少 email_verified 时只有配置 trust_email 才信任邮箱
+	delete(idp.Claims, "email_verified")
+	code = authorize(t, p.AuthURL("state-5", verifier, "nonce-5"), "state-5")
+	_, err = p.Exchange(ctx, code, verifier, "nonce-5")
+	assert.ErrorIs(t, err, ErrEmailNotVerified)
+	p.Config().TrustEmail = true
+	code = authorize(t, p.AuthURL("state-6", verifier, "nonce-6"), "state-6")
+	_, err = p.Exchange(ctx, code, verifier, "nonce-6")
+	assert.NoError(t, err)
+}
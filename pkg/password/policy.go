@@ -0,0 +1,115 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// MaxLength bcrypt 只支持 72 字节以内的密码
+const MaxLength = 72
+
+var ErrBreached = errors.New("密码已出现在泄露密码库中，请更换")
+
+// Policy 密码策略
+type Policy struct {
+	MinLength     int    `yaml:"min_length" json:"min_length,omitempty"`         // 最小长度，默认 8
+	RequireUpper  bool   `yaml:"require_upper" json:"require_upper,omitempty"`   // 必须包含大写字母
+	RequireLower  bool   `yaml:"require_lower" json:"require_lower,omitempty"`   // 必须包含小写字母
+	RequireDigit  bool   `yaml:"require_digit" json:"require_digit,omitempty"`   // 必须包含数字
+	RequireSymbol bool   `yaml:"require_symbol" json:"require_symbol,omitempty"` // 必须包含特殊字符
+	Denylist      string `yaml:"denylist" json:"denylist,omitempty"`             // 泄露密码列表文件路径，每行一个密码，比较时忽略大小写
+
+	once   sync.Once
+	denied map[string]struct{}
+	err    error
+}
+
+// Validate 校验密码是否符合策略
+func (p *Policy) Validate(password string) error {
+	minLength := p.MinLength
+	if minLength <= 0 {
+		minLength = 8
+	}
+	if n := len([]rune(password)); n < minLength {
+		return fmt.Errorf("密码长度不能少于 %d 位", minLength)
+	}
+	if len(password) > MaxLength {
+		return fmt.Errorf("密码长度不能超过 %d 字节", MaxLength)
+	}
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	var missing []string
+	if p.RequireUpper && !upper {
+		missing = append(missing, "大写字母")
+	}
+	if p.RequireLower && !lower {
+		missing = append(missing, "小写字母")
+	}
+	if p.RequireDigit && !digit {
+		missing = append(missing, "数字")
+	}
+	if p.RequireSymbol && !symbol {
+		missing = append(missing, "特殊字符")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("密码必须包含%s", strings.Join(missing, "、"))
+	}
+	breached, err := p.breached(password)
+	if err != nil {
+		return err
+	}
+	if breached {
+		return ErrBreached
+	}
+	return nil
+}
+
+// breached 密码是否在泄露密码列表中，列表文件首次使用时加载
+func (p *Policy) breached(password string) (bool, error) {
+	if p.Denylist == "" {
+		return false, nil
+	}
+	p.once.Do(func() {
+		p.denied, p.err = loadDenylist(p.Denylist)
+	})
+	if p.err != nil {
+		return false, p.err
+	}
+	_, ok := p.denied[strings.ToLower(password)]
+	return ok, nil
+}
+
+func loadDenylist(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("password denylist: %w", err)
+	}
+	defer f.Close()
+	denied := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			denied[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("password denylist: %w", err)
+	}
+	return denied, nil
+}
@@ -0,0 +1,37 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Validate(t *testing.T) {
+	p := &Policy{MinLength: 10, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}
+
+	assert.Error(t, p.Validate("Ab1!"))
+	assert.Error(t, p.Validate(strings.Repeat("Ab1!", 20)))
+	err := p.Validate("abcdefghij")
+	assert.EqualError(t, err, "密码必须包含大写字母、数字、特殊字符")
+	assert.NoError(t, p.Validate("Abcdefgh1!"))
+
+	// 默认最小长度 8
+	assert.Error(t, (&Policy{}).Validate("1234567"))
+	assert.NoError(t, (&Policy{}).Validate("12345678"))
+}
+
+func TestPolicy_Denylist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denylist.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("password1\n\nQwerty123\n"), 0o600))
+	p := &Policy{Denylist: path}
+
+	assert.Equal(t, ErrBreached, p.Validate("Password1"))
+	assert.Equal(t, ErrBreached, p.Validate("qwerty123"))
+	assert.NoError(t, p.Validate("correct horse"))
+
+	// 列表文件不存在时拒绝，避免策略静默失效
+	assert.Error(t, (&Policy{Denylist: filepath.Join(t.TempDir(), "missing")}).Validate("correct horse"))
+}
@@ -0,0 +1,337 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"time"
+)
+
+var (
+	ErrBatchUnsupported = errors.New("queue driver does not support batches")
+	ErrBatchNotFound    = errors.New("batch not found")
+)
+
+// IOutputTask 可选接口，链式任务中实现后其输出会传给下一个任务，下一个任务通过 ChainInput 读取
+type IOutputTask interface {
+	Output() any
+}
+
+// encodedTask 序列化后的任务，用于保存链式任务的后续步骤和批量任务的回调
+type encodedTask struct {
+	Type     string `json:"type"`
+	Data     string `json:"data"`
+	Queue    string `json:"queue,omitempty"`
+	Priority int    `json:"priority,omitempty"`
+}
+
+// workflow 随任务保存的流程信息
+type workflow struct {
+	Chain    []encodedTask   `json:"chain,omitempty"`    // 链式任务的后续步骤
+	Input    json.RawMessage `json:"input,omitempty"`    // 上一个任务的输出
+	BatchID  string          `json:"batch_id,omitempty"` // 所属批次
+	Callback bool            `json:"callback,omitempty"` // 是否为批次回调，回调不计入批次计数
+}
+
+// BatchInfo 批次信息
+type BatchInfo struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Total       int        `json:"total"`     // 任务总数
+	Pending     int        `json:"pending"`   // 未结束的任务数
+	Succeeded   int        `json:"succeeded"` // 执行成功的任务数
+	Failed      int        `json:"failed"`    // 进入死信的任务数
+	Then        string     `json:"then"`      // 全部成功后推送的任务
+	Catch       string     `json:"catch"`     // 首个任务失败时推送的任务
+	Finally     string     `json:"finally"`   // 全部结束后推送的任务
+	CancelledAt *time.Time `json:"cancelled_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Cancelled 批次是否已取消
+func (b *BatchInfo) Cancelled() bool {
+	return b.CancelledAt != nil
+}
+
+// Finished 批次内的任务是否都已结束
+func (b *BatchInfo) Finished() bool {
+	return b.Pending <= 0
+}
+
+// BatchOptions 批次选项，回调任务推送到与批次相同的队列
+type BatchOptions struct {
+	Name    string // 批次名称
+	Then    ITask  // 全部成功后执行
+	Catch   ITask  // 首个任务失败（进入死信）时执行
+	Finally ITask  // 全部结束后执行，无论成功失败
+}
+
+// batchStore 批次存储，由各驱动实现
+type batchStore interface {
+	// createBatch 保存新批次
+	createBatch(b *BatchInfo) error
+	// findBatch 查询批次
+	findBatch(id string) (*BatchInfo, error)
+	// finishBatchJob 记录批次内一个任务结束，返回更新后的批次
+	finishBatchJob(id string, succeeded bool) (*BatchInfo, error)
+	// cancelBatch 取消批次
+	cancelBatch(id string) error
+	// abortBatch 推送中断时从总数和未结束数中扣除未推送的 missing 个任务并取消批次
+	abortBatch(id string, missing int) error
+}
+
+// withWorkflow 随任务保存流程信息
+func withWorkflow(wf *workflow) PushOption {
+	return func(o *PushOptions) {
+		data, _ := json.Marshal(wf)
+		o.workflow = string(data)
+	}
+}
+
+// encodeTask 序列化任务及其投递的队列
+func encodeTask(task ITask, o *PushOptions) (encodedTask, error) {
+	typeName, data, err := encode(task)
+	if err != nil {
+		return encodedTask{}, err
+	}
+	return encodedTask{Type: typeName, Data: data, Queue: o.Queue, Priority: o.Priority}, nil
+}
+
+// Chain 链式任务，依次执行 tasks，前一个成功后才推送下一个，返回第一个任务的 ID
+//
+//	任一任务重试耗尽进入死信后，后续任务不再执行；前一个任务实现 IOutputTask 时，其输出通过 ChainInput 传给下一个任务
+func Chain(q IQueue, tasks []ITask, opts ...PushOption) (uint, error) {
+	if len(tasks) == 0 {
+		return 0, errors.New("chain is empty")
+	}
+	o := NewPushOptions(opts...)
+	wf := &workflow{}
+	for _, task := range tasks[1:] {
+		e, err := encodeTask(task, o)
+		if err != nil {
+			return 0, err
+		}
+		wf.Chain = append(wf.Chain, e)
+	}
+	return q.Push(tasks[0], 0, append(opts, withWorkflow(wf))...)
+}
+
+// Batch 批量任务，所有任务并发执行，全部结束后按结果推送回调任务
+func Batch(q IQueue, tasks []ITask, bo BatchOptions, opts ...PushOption) (*BatchInfo, error) {
+	store, ok := q.(batchStore)
+	if !ok {
+		return nil, ErrBatchUnsupported
+	}
+	if len(tasks) == 0 {
+		return nil, errors.New("batch is empty")
+	}
+	o := NewPushOptions(opts...)
+	batch := &BatchInfo{
+		ID:        uuid.NewString(),
+		Name:      bo.Name,
+		Total:     len(tasks),
+		Pending:   len(tasks),
+		CreatedAt: time.Now(),
+	}
+	for _, cb := range []struct {
+		task ITask
+		dst  *string
+	}{{bo.Then, &batch.Then}, {bo.Catch, &batch.Catch}, {bo.Finally, &batch.Finally}} {
+		if cb.task == nil {
+			continue
+		}
+		e, err := encodeTask(cb.task, o)
+		if err != nil {
+			return nil, err
+		}
+		data, _ := json.Marshal(e)
+		*cb.dst = string(data)
+	}
+	if err := store.createBatch(batch); err != nil {
+		return nil, err
+	}
+	opts = append(opts, withWorkflow(&workflow{BatchID: batch.ID}))
+	for i, task := range tasks {
+		if _, err := q.Push(task, 0, opts...); err != nil {
+			// 未推送的任务永远不会结束，扣除后已推送的任务结束时批次仍能完成
+			if abortErr := store.abortBatch(batch.ID, len(tasks)-i); abortErr != nil {
+				slog.Warn("[QUEUE] abort batch error", slog.Any("err", abortErr), slog.String("batch", batch.ID))
+			} else if b, findErr := store.findBatch(batch.ID); findErr == nil {
+				batch = b
+			}
+			return batch, fmt.Errorf("push batch task error: %w", err)
+		}
+	}
+	return batch, nil
+}
+
+// FindBatch 查询批次
+func FindBatch(q IQueue, id string) (*BatchInfo, error) {
+	store, ok := q.(batchStore)
+	if !ok {
+		return nil, ErrBatchUnsupported
+	}
+	return store.findBatch(id)
+}
+
+// CancelBatch 取消批次，已推送的任务仍会执行，任务内可通过 BatchCancelled 判断后提前返回
+func CancelBatch(q IQueue, id string) error {
+	store, ok := q.(batchStore)
+	if !ok {
+		return ErrBatchUnsupported
+	}
+	return store.cancelBatch(id)
+}
+
+type workflowKey struct{}
+
+// workflowContext 执行任务时 context 中保存的流程信息
+type workflowContext struct {
+	wf    *workflow
+	store batchStore
+}
+
+// ChainInput 读取链式任务中上一个任务的输出，没有输出时 v 保持不变
+func ChainInput(ctx context.Context, v any) error {
+	wc, ok := ctx.Value(workflowKey{}).(*workflowContext)
+	if !ok || len(wc.wf.Input) == 0 {
+		return nil
+	}
+	return json.Unmarshal(wc.wf.Input, v)
+}
+
+// GetBatchID 获取当前任务所属的批次 ID，不属于批次时返回空
+func GetBatchID(ctx context.Context) string {
+	if wc, ok := ctx.Value(workflowKey{}).(*workflowContext); ok {
+		return wc.wf.BatchID
+	}
+	return ""
+}
+
+// BatchCancelled 判断当前任务所属的批次是否已取消
+func BatchCancelled(ctx context.Context) bool {
+	wc, ok := ctx.Value(workflowKey{}).(*workflowContext)
+	if !ok || wc.wf.BatchID == "" || wc.store == nil {
+		return false
+	}
+	batch, err := wc.store.findBatch(wc.wf.BatchID)
+	if err != nil {
+		return false
+	}
+	return batch.Cancelled()
+}
+
+// detachBatch 手动重试死信时移除批次信息，任务进入死信时已计入批次，重试后结束不再重复计数
+func detachBatch(raw string) string {
+	var wf workflow
+	if raw == "" || json.Unmarshal([]byte(raw), &wf) != nil || wf.BatchID == "" || wf.Callback {
+		return raw
+	}
+	wf.BatchID = ""
+	if len(wf.Chain) == 0 && len(wf.Input) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(&wf)
+	return string(data)
+}
+
+// parseWorkflow 解析任务的流程信息，没有时返回 nil
+func parseWorkflow(job *Job) *workflow {
+	if job.Workflow == "" {
+		return nil
+	}
+	var wf workflow
+	if err := json.Unmarshal([]byte(job.Workflow), &wf); err != nil {
+		slog.Warn("[QUEUE] parse workflow error", slog.Any("err", err), slog.Uint64("id", uint64(job.ID)))
+		return nil
+	}
+	return &wf
+}
+
+// withWorkflowContext 将流程信息写入任务的 context
+func (w *worker) withWorkflowContext(ctx context.Context, wf *workflow) context.Context {
+	if wf == nil {
+		return ctx
+	}
+	store, _ := w.driver.(batchStore)
+	return context.WithValue(ctx, workflowKey{}, &workflowContext{wf: wf, store: store})
+}
+
+// pushEncoded 推送序列化的任务
+func (w *worker) pushEncoded(e encodedTask, requestID string, wf *workflow) error {
+	task, err := w.decode(&Job{Type: e.Type, Data: e.Data})
+	if err != nil {
+		return err
+	}
+	opts := []PushOption{WithQueue(e.Queue), WithPriority(e.Priority), WithRequestID(requestID)}
+	if wf != nil {
+		opts = append(opts, withWorkflow(wf))
+	}
+	_, err = w.queue.Push(task, 0, opts...)
+	return err
+}
+
+// advance 任务结束后推进流程：成功时推送链式任务的下一步，并更新所属批次
+//
+//	task 为成功执行的任务，失败时为 nil
+func (w *worker) advance(job *Job, wf *workflow, task ITask) {
+	if wf == nil || wf.Callback {
+		return
+	}
+	if task != nil && len(wf.Chain) > 0 {
+		next := &workflow{Chain: wf.Chain[1:]}
+		if t, ok := task.(IOutputTask); ok {
+			if output, err := json.Marshal(t.Output()); err == nil {
+				next.Input = output
+			} else {
+				slog.Warn(w.name+" encode chain output error", slog.Any("err", err), slog.String("type", job.Type))
+			}
+		}
+		if err := w.pushEncoded(wf.Chain[0], job.RequestID, next); err != nil {
+			slog.Warn(w.name+" push chain task error", slog.Any("err", err), slog.String("type", wf.Chain[0].Type))
+		}
+	}
+	if wf.BatchID != "" {
+		w.advanceBatch(job, wf.BatchID, task != nil)
+	}
+}
+
+// advanceBatch 更新批次计数，首个失败时推送 Catch，全部结束后推送 Then 和 Finally
+func (w *worker) advanceBatch(job *Job, id string, succeeded bool) {
+	store, ok := w.driver.(batchStore)
+	if !ok {
+		return
+	}
+	batch, err := store.finishBatchJob(id, succeeded)
+	if err != nil {
+		slog.Warn(w.name+" update batch error", slog.Any("err", err), slog.String("batch", id))
+		return
+	}
+	var callbacks []string
+	if !succeeded && batch.Failed == 1 {
+		callbacks = append(callbacks, batch.Catch)
+	}
+	if batch.Finished() {
+		if batch.Failed == 0 {
+			callbacks = append(callbacks, batch.Then)
+		}
+		callbacks = append(callbacks, batch.Finally)
+	}
+	for _, cb := range callbacks {
+		if cb == "" {
+			continue
+		}
+		var e encodedTask
+		if err := json.Unmarshal([]byte(cb), &e); err != nil {
+			slog.Warn(w.name+" decode batch callback error", slog.Any("err", err), slog.String("batch", id))
+			continue
+		}
+		if err := w.pushEncoded(e, job.RequestID, &workflow{BatchID: id, Callback: true}); err != nil {
+			slog.Warn(w.name+" push batch callback error", slog.Any("err", err), slog.String("batch", id))
+		}
+	}
+}
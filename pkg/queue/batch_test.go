@@ -0,0 +1,218 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var executed []string
+
+// stepTask 将上一个任务的输出累加到 Value 并记录
+type stepTask struct {
+	Name  string `json:"name"`
+	Value int    `json:"value"`
+}
+
+func (s *stepTask) Execute(ctx context.Context, q IQueue) error {
+	var input int
+	if err := ChainInput(ctx, &input); err != nil {
+		return err
+	}
+	s.Value += input
+	executed = append(executed, fmt.Sprintf("%s:%d", s.Name, s.Value))
+	return nil
+}
+
+func (s *stepTask) Output() any {
+	return s.Value
+}
+
+// cancelTask 记录所属批次是否已取消
+type cancelTask struct{}
+
+func (c *cancelTask) Execute(ctx context.Context, q IQueue) error {
+	executed = append(executed, fmt.Sprintf("cancelled:%v", BatchCancelled(ctx)))
+	return nil
+}
+
+// badTask 无法序列化，推送时失败
+type badTask struct {
+	C chan int `json:"c"`
+}
+
+func (b *badTask) Execute(ctx context.Context, q IQueue) error {
+	return nil
+}
+
+// flakyTask 在 flakyFail 为 true 时失败，用于测试死信重试
+type flakyTask struct{}
+
+var flakyFail bool
+
+func (f *flakyTask) Execute(ctx context.Context, q IQueue) error {
+	if flakyFail {
+		return errors.New("flaky")
+	}
+	executed = append(executed, "flaky")
+	return nil
+}
+
+func (f *flakyTask) RetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+// drain 依次执行所有到期任务
+func drain(w *worker) {
+	for {
+		jobs, _ := w.reserveBatch(10)
+		if len(jobs) == 0 {
+			return
+		}
+		for _, job := range jobs {
+			w.execute(context.Background(), job)
+		}
+	}
+}
+
+// testWorkflow 各驱动共用的链式和批量任务测试
+func testWorkflow(t *testing.T, q IQueue, w *worker) {
+	for _, task := range []ITask{&stepTask{}, &failTask{}, &cancelTask{}} {
+		require.NoError(t, q.Register(task))
+	}
+
+	// 链式任务依次执行，并传递上一个任务的输出
+	executed = nil
+	_, err := Chain(q, []ITask{&stepTask{Name: "a", Value: 1}, &stepTask{Name: "b", Value: 10}, &stepTask{Name: "c", Value: 100}})
+	require.NoError(t, err)
+	drain(w)
+	assert.Equal(t, []string{"a:1", "b:11", "c:111"}, executed)
+
+	// 全部成功时执行 Then 和 Finally
+	executed = nil
+	batch, err := Batch(q, []ITask{&stepTask{Name: "x"}, &stepTask{Name: "y"}}, BatchOptions{
+		Then:    &stepTask{Name: "then"},
+		Catch:   &stepTask{Name: "catch"},
+		Finally: &stepTask{Name: "finally"},
+	})
+	require.NoError(t, err)
+	drain(w)
+	assert.Equal(t, []string{"x:0", "y:0", "then:0", "finally:0"}, executed)
+	batch, err = FindBatch(q, batch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, batch.Succeeded)
+	assert.True(t, batch.Finished())
+	assert.NotNil(t, batch.FinishedAt)
+
+	// 有任务失败时执行 Catch 和 Finally
+	executed = nil
+	batch, err = Batch(q, []ITask{&failTask{}, &stepTask{Name: "x"}}, BatchOptions{
+		Then:    &stepTask{Name: "then"},
+		Catch:   &stepTask{Name: "catch"},
+		Finally: &stepTask{Name: "finally"},
+	})
+	require.NoError(t, err)
+	drain(w)
+	assert.ElementsMatch(t, []string{"catch:0", "x:0", "finally:0"}, executed)
+	batch, err = FindBatch(q, batch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, batch.Failed)
+	assert.Equal(t, 1, batch.Succeeded)
+
+	// 任务可以判断批次是否已取消
+	executed = nil
+	batch, err = Batch(q, []ITask{&cancelTask{}}, BatchOptions{})
+	require.NoError(t, err)
+	require.NoError(t, CancelBatch(q, batch.ID))
+	drain(w)
+	assert.Equal(t, []string{"cancelled:true"}, executed)
+
+	// 推送中断时只统计已推送的任务，批次仍能结束
+	executed = nil
+	batch, err = Batch(q, []ITask{&stepTask{Name: "x"}, &badTask{}, &stepTask{Name: "y"}}, BatchOptions{Finally: &stepTask{Name: "finally"}})
+	require.Error(t, err)
+	assert.Equal(t, 1, batch.Total)
+	assert.True(t, batch.Cancelled())
+	drain(w)
+	assert.Equal(t, []string{"x:0", "finally:0"}, executed)
+	batch, err = FindBatch(q, batch.ID)
+	require.NoError(t, err)
+	assert.True(t, batch.Finished())
+	assert.Equal(t, 1, batch.Succeeded)
+
+	_, err = FindBatch(q, "missing")
+	assert.ErrorIs(t, err, ErrBatchNotFound)
+}
+
+// testRetryFailedInBatch 死信重试成功后不重复计入批次，其他任务仍未结束时不触发回调
+func testRetryFailedInBatch(t *testing.T, q IQueue, w *worker, retry func() error) {
+	for _, task := range []ITask{&stepTask{}, &flakyTask{}} {
+		require.NoError(t, q.Register(task))
+	}
+	executed = nil
+	flakyFail = true
+	batch, err := Batch(q, []ITask{&flakyTask{}, &stepTask{Name: "x"}, &stepTask{Name: "y"}}, BatchOptions{
+		Then:    &stepTask{Name: "then"},
+		Finally: &stepTask{Name: "finally"},
+	})
+	require.NoError(t, err)
+
+	// 首个任务失败进入死信，其他任务领取后暂不执行
+	jobs, err := w.reserveBatch(1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	w.execute(context.Background(), jobs[0])
+	pending, err := w.reserveBatch(2)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+
+	flakyFail = false
+	require.NoError(t, retry())
+	drain(w)
+	assert.Equal(t, []string{"flaky"}, executed)
+	info, err := FindBatch(q, batch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, info.Pending)
+	assert.Equal(t, 1, info.Failed)
+
+	// 其余任务结束后批次完成
+	for _, job := range pending {
+		w.execute(context.Background(), job)
+	}
+	drain(w)
+	assert.Equal(t, []string{"flaky", "x:0", "y:0", "finally:0"}, executed)
+	info, err = FindBatch(q, batch.ID)
+	require.NoError(t, err)
+	assert.True(t, info.Finished())
+	assert.Equal(t, 2, info.Succeeded)
+}
+
+func TestGorm_Workflow(t *testing.T) {
+	q := newTestGorm(t, &Config{})
+	testWorkflow(t, q, q.worker)
+}
+
+func TestGorm_RetryFailedInBatch(t *testing.T) {
+	q := newTestGorm(t, &Config{})
+	testRetryFailedInBatch(t, q, q.worker, func() error {
+		var failed SysFailedTask
+		if err := q.db.First(&failed).Error; err != nil {
+			return err
+		}
+		return q.RetryFailed(failed.ID)
+	})
+}
+
+func TestRedis_Workflow(t *testing.T) {
+	q, _ := newTestRedis(t, &Config{})
+	testWorkflow(t, q, q.worker)
+}
+
+func TestMemory_Workflow(t *testing.T) {
+	q := newTestMemory(t)
+	testWorkflow(t, q, q.worker)
+}
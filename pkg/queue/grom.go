@@ -1,147 +1,467 @@
 package queue
 
 import (
-	"context"
-	"encoding/json"
-	"fmt"
+	"errors"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
-	"log/slog"
-	"reflect"
-	"sync"
 	"time"
 )
 
+// TaskStatus 任务状态
+type TaskStatus string
+
+const (
+	TaskStatusPending  TaskStatus = "pending"  // 等待执行
+	TaskStatusReserved TaskStatus = "reserved" // 已被 worker 领取，执行中
+	TaskStatusDone     TaskStatus = "done"     // 已结束，去重窗口未到期前保留
+)
+
 type SysTask struct {
-	ID        uint      `gorm:"primaryKey"`
-	Type      string    `gorm:"size:255;index"`
-	Data      string    `gorm:"type:text"`
-	RunAt     time.Time `gorm:"index"`
-	ErrorMsg  string    `gorm:"type:text"`
-	CreatedAt time.Time
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Type        string     `gorm:"size:255;index" json:"type"`
+	Data        string     `gorm:"type:text" json:"data"`
+	Queue       string     `gorm:"size:100;default:'default';index:idx_task_fetch,priority:1" json:"queue"`
+	Priority    int        `gorm:"default:0;index:idx_task_fetch,priority:3" json:"priority"` // 优先级，数值越大越先执行
+	Status      TaskStatus `gorm:"size:20;default:'pending';index:idx_task_status_run_at,priority:1;index:idx_task_fetch,priority:2" json:"status"`
+	RunAt       time.Time  `gorm:"index;index:idx_task_status_run_at,priority:2;index:idx_task_fetch,priority:4" json:"run_at"`
+	ReservedAt  *time.Time `gorm:"index" json:"reserved_at"`              // 领取时间，租约从此刻开始计算
+	ReservedBy  string     `gorm:"size:100" json:"reserved_by"`           // 领取任务的 worker 标识
+	Attempts    int        `gorm:"default:0" json:"attempts"`             // 已执行次数
+	ErrorMsg    string     `gorm:"type:text" json:"error_msg"`            // 上一次执行的错误
+	UniqueKey   *string    `gorm:"size:64;uniqueIndex" json:"unique_key"` // 去重标识，为空表示不去重
+	UniqueUntil *time.Time `json:"unique_until"`                          // 去重窗口截止时间
+	RequestID   string     `gorm:"size:64" json:"request_id"`             // 推送任务的请求 ID
+	Workflow    string     `gorm:"type:text" json:"workflow"`             // 链式、批量任务的流程信息
+	CreatedAt   time.Time  `json:"created_at"`
 }
 
-type Gorm struct {
-	db       *gorm.DB
-	registry map[string]reflect.Type
-	mu       sync.RWMutex
-	ctx      context.Context
-	cancel   context.CancelFunc
-	wg       sync.WaitGroup
+// SysFailedTask 死信表，保存重试耗尽的任务
+type SysFailedTask struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Queue     string    `gorm:"size:100;default:'default';index" json:"queue"`
+	Priority  int       `gorm:"default:0" json:"priority"`
+	Type      string    `gorm:"size:255;index" json:"type"`
+	Data      string    `gorm:"type:text" json:"data"`
+	Attempts  int       `gorm:"default:0" json:"attempts"`
+	ErrorMsg  string    `gorm:"type:text" json:"error_msg"` // 最后一次执行的错误
+	RequestID string    `gorm:"size:64" json:"request_id"`  // 推送任务的请求 ID
+	Workflow  string    `gorm:"type:text" json:"workflow"`  // 链式、批量任务的流程信息
+	FailedAt  time.Time `gorm:"index" json:"failed_at"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
-// NewGormQueue 创建队列实例
-func NewGormQueue(db *gorm.DB) IQueue {
-	_ = db.AutoMigrate(&SysTask{})
-	ctx, cancel := context.WithCancel(context.Background())
-	return &Gorm{
-		db:       db,
-		registry: make(map[string]reflect.Type),
-		ctx:      ctx,
-		cancel:   cancel,
+// SysBatch 批量任务记录
+type SysBatch struct {
+	ID          string     `gorm:"primaryKey;size:36" json:"id"`
+	Name        string     `gorm:"size:255" json:"name"`
+	Total       int        `json:"total"`                    // 任务总数
+	Pending     int        `json:"pending"`                  // 未结束的任务数
+	Succeeded   int        `json:"succeeded"`                // 执行成功的任务数
+	Failed      int        `json:"failed"`                   // 进入死信的任务数
+	Then        string     `gorm:"type:text" json:"then"`    // 全部成功后推送的任务
+	Catch       string     `gorm:"type:text" json:"catch"`   // 首个任务失败时推送的任务
+	Finally     string     `gorm:"type:text" json:"finally"` // 全部结束后推送的任务
+	CancelledAt *time.Time `json:"cancelled_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// toBatch 转换为 BatchInfo
+func (b *SysBatch) toBatch() *BatchInfo {
+	info := BatchInfo(*b)
+	return &info
+}
+
+// toJob 转换为 Job
+func (t *SysTask) toJob() *Job {
+	return &Job{
+		ID:        t.ID,
+		Queue:     t.Queue,
+		Priority:  t.Priority,
+		Type:      t.Type,
+		Data:      t.Data,
+		Attempts:  t.Attempts,
+		ErrorMsg:  t.ErrorMsg,
+		RequestID: t.RequestID,
+		Workflow:  t.Workflow,
+		RunAt:     t.RunAt,
 	}
 }
 
-// Register 注册任务类型
-func (q *Gorm) Register(task ITask) error {
-	typeName, err := GetTaskTypeName(task)
+type Gorm struct {
+	*worker
+	db *gorm.DB
+}
+
+// NewGormQueue 创建队列实例
+func NewGormQueue(db *gorm.DB, cfg *Config) (IQueue, error) {
+	w, err := newWorker("[GORM QUEUE]", cfg)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	q.mu.Lock()
-	defer q.mu.Unlock()
-	q.registry[typeName] = reflect.TypeOf(task).Elem()
-	return nil
+	_ = db.AutoMigrate(&SysTask{}, &SysFailedTask{}, &SysBatch{})
+	q := &Gorm{worker: w, db: db}
+	w.driver = q
+	w.queue = q
+	return q, nil
+}
+
+// DB 返回队列使用的数据库连接
+func (q *Gorm) DB() *gorm.DB {
+	return q.db
 }
 
 // Push 推送任务
-func (q *Gorm) Push(task ITask, delay time.Duration) error {
-	typeName, err := GetTaskTypeName(task)
-	if err != nil {
-		return err
-	}
-	data, err := json.Marshal(task)
+func (q *Gorm) Push(task ITask, delay time.Duration, opts ...PushOption) (uint, error) {
+	typeName, data, err := encode(task)
 	if err != nil {
-		return err
+		return 0, err
 	}
+	o := NewPushOptions(opts...)
+	now := time.Now()
 	model := SysTask{
-		Type:  typeName,
-		Data:  string(data),
-		RunAt: time.Now().Add(delay),
+		Queue:     o.Queue,
+		Priority:  o.Priority,
+		Type:      typeName,
+		Data:      data,
+		Status:    TaskStatusPending,
+		RunAt:     now.Add(delay),
+		RequestID: o.RequestID,
+		Workflow:  o.workflow,
 	}
-	return q.db.Create(&model).Error
+	uid := o.uniqueID(typeName, data)
+	if uid == "" {
+		if err := q.db.Create(&model).Error; err != nil {
+			return 0, err
+		}
+		metricEnqueued.WithLabelValues(typeName, o.Queue).Inc()
+		return model.ID, nil
+	}
+	model.UniqueKey = &uid
+	if o.UniqueTTL > 0 {
+		until := now.Add(o.UniqueTTL)
+		model.UniqueUntil = &until
+	}
+	return q.pushUnique(&model, now)
+}
+
+// pushUnique 推送去重任务，已有相同去重标识的任务时返回其 ID
+//
+//	并发推送时由唯一索引保证只有一条写入成功，失败方重新查询已有任务
+func (q *Gorm) pushUnique(model *SysTask, now time.Time) (uint, error) {
+	var err error
+	for i := 0; i < 2; i++ {
+		var exist SysTask
+		err = q.db.Where("unique_key = ?", *model.UniqueKey).Take(&exist).Error
+		if err == nil {
+			if exist.Status != TaskStatusDone || (exist.UniqueUntil != nil && exist.UniqueUntil.After(now)) {
+				return exist.ID, nil
+			}
+			// 去重窗口已过期，删除旧记录后重新写入
+			if err = q.db.Where("id = ? AND status = ?", exist.ID, TaskStatusDone).Delete(&SysTask{}).Error; err != nil {
+				return 0, err
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, err
+		}
+		model.ID = 0
+		if err = q.db.Create(model).Error; err == nil {
+			metricEnqueued.WithLabelValues(model.Type, model.Queue).Inc()
+			return model.ID, nil
+		}
+	}
+	return 0, err
 }
 
-func (q *Gorm) Pop() (ITask, error) {
-	var model SysTask
+// reserve 领取最多 n 条到期任务
+//
+//	任务不会被删除，而是标记为 reserved，执行成功后 ack 才会删除
+func (q *Gorm) reserve(queue string, n int) ([]*Job, error) {
+	var reserved []*Job
 	err := q.db.Transaction(func(tx *gorm.DB) error {
-		// 查出一条可执行任务
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("run_at <= ?", time.Now()).
+		// 查出可执行任务，多个 worker 并发时跳过已被锁定的行
+		var models []*SysTask
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND run_at <= ?", TaskStatusPending, time.Now())
+		if queue != "" {
+			query = query.Where("queue = ?", queue)
+		}
+		if err := query.Order("priority DESC").
 			Order("run_at ASC").
-			First(&model).Error; err != nil {
+			Limit(n).
+			Find(&models).Error; err != nil {
 			return err
 		}
-		// 删除任务
-		if err := tx.Delete(&model).Error; err != nil {
-			return err
+		now := time.Now()
+		for _, model := range models {
+			// 标记为已领取，带上状态条件防止被其他 worker 重复领取
+			result := tx.Model(&SysTask{}).
+				Where("id = ? AND status = ?", model.ID, TaskStatusPending).
+				Updates(map[string]any{
+					"status":      TaskStatusReserved,
+					"reserved_at": now,
+					"reserved_by": q.id,
+					"attempts":    gorm.Expr("attempts + 1"),
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+			model.Attempts++
+			reserved = append(reserved, model.toJob())
 		}
 		return nil
 	})
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, nil
+	return reserved, err
+}
+
+// ack 确认任务执行成功并删除
+func (q *Gorm) ack(job *Job) error {
+	return q.finish(q.db, job.ID)
+}
+
+// finish 结束当前 worker 持有的任务，去重窗口未到期的任务标记为 done 继续占用去重标识，其余直接删除
+//
+//	任务已被回收或由其他 worker 领取时返回 ErrLeaseLost
+func (q *Gorm) finish(db *gorm.DB, id uint) error {
+	result := db.Model(&SysTask{}).
+		Where("id = ? AND reserved_by = ? AND unique_until > ?", id, q.id, time.Now()).
+		Updates(map[string]any{
+			"status":      TaskStatusDone,
+			"reserved_at": nil,
+			"reserved_by": "",
+		})
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	result = db.Where("id = ? AND reserved_by = ?", id, q.id).Delete(&SysTask{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// retry 放回队列，delay 后再次执行
+func (q *Gorm) retry(job *Job, delay time.Duration, execErr error) error {
+	result := q.db.Model(&SysTask{}).
+		Where("id = ? AND reserved_by = ?", job.ID, q.id).
+		Updates(map[string]any{
+			"status":      TaskStatusPending,
+			"run_at":      time.Now().Add(delay),
+			"reserved_at": nil,
+			"reserved_by": "",
+			"error_msg":   execErr.Error(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// bury 从任务表移入死信表，租约失效时回滚死信写入
+func (q *Gorm) bury(job *Job, execErr error) error {
+	return q.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&SysFailedTask{
+			Queue:     job.Queue,
+			Priority:  job.Priority,
+			Type:      job.Type,
+			Data:      job.Data,
+			Attempts:  job.Attempts,
+			ErrorMsg:  execErr.Error(),
+			RequestID: job.RequestID,
+			Workflow:  job.Workflow,
+			FailedAt:  time.Now(),
+		}).Error; err != nil {
+			return err
 		}
+		return q.finish(tx, job.ID)
+	})
+}
+
+// touch 续约执行中的任务
+func (q *Gorm) touch(job *Job) error {
+	return q.db.Model(&SysTask{}).
+		Where("id = ? AND reserved_by = ?", job.ID, q.id).
+		Update("reserved_at", time.Now()).Error
+}
+
+// reap 将租约过期的任务放回队列
+func (q *Gorm) reap(lease time.Duration) (int64, error) {
+	result := q.db.Model(&SysTask{}).
+		Where("status = ? AND reserved_at < ?", TaskStatusReserved, time.Now().Add(-lease)).
+		Updates(map[string]any{
+			"status":      TaskStatusPending,
+			"reserved_at": nil,
+			"reserved_by": "",
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	// 清理去重窗口已过期的任务
+	if err := q.db.Where("status = ? AND unique_until <= ?", TaskStatusDone, time.Now()).Delete(&SysTask{}).Error; err != nil {
+		return result.RowsAffected, err
+	}
+	return result.RowsAffected, nil
+}
+
+// release 将被中断的任务立即放回队列，本次执行不计入执行次数
+func (q *Gorm) release(job *Job) error {
+	return q.db.Model(&SysTask{}).
+		Where("id = ? AND reserved_by = ?", job.ID, q.id).
+		Updates(map[string]any{
+			"status":      TaskStatusPending,
+			"run_at":      time.Now(),
+			"reserved_at": nil,
+			"reserved_by": "",
+			"attempts":    gorm.Expr("attempts - 1"),
+		}).Error
+}
+
+// depth 按队列和状态统计未完成的任务数
+func (q *Gorm) depth() (map[string]map[TaskStatus]int64, error) {
+	var rows []struct {
+		Queue  string
+		Status TaskStatus
+		Count  int64
+	}
+	if err := q.db.Model(&SysTask{}).
+		Select("queue, status, COUNT(*) AS count").
+		Where("status IN ?", []TaskStatus{TaskStatusPending, TaskStatusReserved}).
+		Group("queue, status").
+		Scan(&rows).Error; err != nil {
 		return nil, err
 	}
-	// 获取任务类型
-	q.mu.RLock()
-	typ, ok := q.registry[model.Type]
-	q.mu.RUnlock()
-	if !ok {
-		return nil, fmt.Errorf("unregistered task type: %s", model.Type)
+	depth := make(map[string]map[TaskStatus]int64)
+	for _, row := range rows {
+		if depth[row.Queue] == nil {
+			depth[row.Queue] = make(map[TaskStatus]int64)
+		}
+		depth[row.Queue][row.Status] = row.Count
 	}
+	return depth, nil
+}
+
+// RetryFailed 将死信任务放回原队列立即执行，执行次数重新计算，不再计入原批次
+func (q *Gorm) RetryFailed(id uint) error {
+	return q.db.Transaction(func(tx *gorm.DB) error {
+		var failed SysFailedTask
+		if err := tx.First(&failed, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&SysTask{
+			Queue:     failed.Queue,
+			Priority:  failed.Priority,
+			Type:      failed.Type,
+			Data:      failed.Data,
+			Status:    TaskStatusPending,
+			RunAt:     time.Now(),
+			ErrorMsg:  failed.ErrorMsg,
+			RequestID: failed.RequestID,
+			Workflow:  detachBatch(failed.Workflow),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&failed).Error
+	})
+}
+
+// createBatch 保存新批次
+func (q *Gorm) createBatch(b *BatchInfo) error {
+	model := SysBatch(*b)
+	return q.db.Create(&model).Error
+}
 
-	// 反序列化任务数据
-	task := reflect.New(typ).Interface().(ITask)
-	if err := json.Unmarshal([]byte(model.Data), task); err != nil {
+// findBatch 查询批次
+func (q *Gorm) findBatch(id string) (*BatchInfo, error) {
+	var model SysBatch
+	if err := q.db.Where("id = ?", id).Take(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBatchNotFound
+		}
 		return nil, err
 	}
+	return model.toBatch(), nil
+}
 
-	return task, nil
+// finishBatchJob 记录批次内一个任务结束，计数在同一事务中更新并读取
+func (q *Gorm) finishBatchJob(id string, succeeded bool) (*BatchInfo, error) {
+	column := "failed"
+	if succeeded {
+		column = "succeeded"
+	}
+	var model SysBatch
+	err := q.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&SysBatch{}).
+			Where("id = ? AND pending > 0", id).
+			Updates(map[string]any{
+				"pending": gorm.Expr("pending - 1"),
+				column:    gorm.Expr(column + " + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrBatchNotFound
+		}
+		if err := tx.Where("id = ?", id).Take(&model).Error; err != nil {
+			return err
+		}
+		if model.Pending == 0 {
+			now := time.Now()
+			model.FinishedAt = &now
+			return tx.Model(&model).Update("finished_at", now).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return model.toBatch(), nil
 }
 
-// Start 启动队列
-func (q *Gorm) Start(ctx context.Context, interval time.Duration) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-q.ctx.Done():
-			slog.Warn("[GORM QUEUE] Stopped")
-			return
-		case <-ticker.C:
-			task, err := q.Pop()
-			if err != nil {
-				slog.Warn("[GORM QUEUE] pop error", slog.Any("err", err))
-				continue
-			}
-			if task == nil {
-				continue
-			}
-			q.wg.Add(1)
-			go func(task ITask) {
-				err := task.Execute(ctx, q)
-				if err != nil {
-					slog.Warn("[GORM QUEUE] Execute task error", slog.Any("err", err))
-				}
-			}(task)
+// cancelBatch 取消批次
+func (q *Gorm) cancelBatch(id string) error {
+	result := q.db.Model(&SysBatch{}).
+		Where("id = ? AND cancelled_at IS NULL", id).
+		Update("cancelled_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := q.findBatch(id); err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
-// Stop 停止队列
-func (q *Gorm) Stop() {
-	q.cancel()
-	q.wg.Wait()
+// abortBatch 推送中断时扣除未推送的任务并取消批次
+func (q *Gorm) abortBatch(id string, missing int) error {
+	now := time.Now()
+	return q.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&SysBatch{}).
+			Where("id = ?", id).
+			Updates(map[string]any{
+				"total":        gorm.Expr("total - ?", missing),
+				"pending":      gorm.Expr("pending - ?", missing),
+				"cancelled_at": gorm.Expr("COALESCE(cancelled_at, ?)", now),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrBatchNotFound
+		}
+		return tx.Model(&SysBatch{}).
+			Where("id = ? AND pending <= 0 AND finished_at IS NULL", id).
+			Update("finished_at", now).Error
+	})
 }
@@ -0,0 +1,232 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type mockTask struct {
+	Name string `json:"name"`
+}
+
+func (m *mockTask) Execute(ctx context.Context, q IQueue) error {
+	return nil
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	return db
+}
+
+func newTestGorm(t *testing.T, cfg *Config) *Gorm {
+	iq, err := NewGormQueue(newTestDB(t), cfg)
+	require.NoError(t, err)
+	q := iq.(*Gorm)
+	require.NoError(t, q.Register(&mockTask{}))
+	return q
+}
+
+// mustPush 推送任务并断言成功，返回任务 ID
+func mustPush(t *testing.T, q IQueue, task ITask, delay time.Duration, opts ...PushOption) uint {
+	id, err := q.Push(task, delay, opts...)
+	require.NoError(t, err)
+	return id
+}
+
+// jobNames 解析任务名称
+func jobNames(t *testing.T, w *worker, jobs []*Job) []string {
+	var names []string
+	for _, job := range jobs {
+		task, err := w.decode(job)
+		require.NoError(t, err)
+		names = append(names, task.(*mockTask).Name)
+	}
+	return names
+}
+
+func TestGorm_ReserveAck(t *testing.T) {
+	q := newTestGorm(t, &Config{})
+	mustPush(t, q, &mockTask{Name: "a"}, 0)
+
+	jobs, err := q.reserveBatch(1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, []string{"a"}, jobNames(t, q.worker, jobs))
+	assert.Equal(t, 1, jobs[0].Attempts)
+
+	// 已领取的任务不会被再次取出
+	again, err := q.reserveBatch(1)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	require.NoError(t, q.ack(jobs[0]))
+	var count int64
+	q.db.Model(&SysTask{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestGorm_RetryAndBury(t *testing.T) {
+	q := newTestGorm(t, &Config{})
+	mustPush(t, q, &mockTask{Name: "a"}, 0)
+	policy := RetryPolicy{MaxAttempts: 2}
+
+	jobs, err := q.reserveBatch(1)
+	require.NoError(t, err)
+	require.NoError(t, q.fail(jobs[0], policy, errors.New("boom")))
+
+	jobs, err = q.reserveBatch(1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, 2, jobs[0].Attempts)
+	assert.Equal(t, "boom", jobs[0].ErrorMsg)
+
+	// 重试耗尽后进入死信表
+	require.NoError(t, q.fail(jobs[0], policy, errors.New("boom again")))
+	var failed SysFailedTask
+	require.NoError(t, q.db.First(&failed).Error)
+	assert.Equal(t, 2, failed.Attempts)
+	assert.Equal(t, "boom again", failed.ErrorMsg)
+	var count int64
+	q.db.Model(&SysTask{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// 死信重新入队后从头计算执行次数
+	require.NoError(t, q.RetryFailed(failed.ID))
+	jobs, err = q.reserveBatch(1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, 1, jobs[0].Attempts)
+	q.db.Model(&SysFailedTask{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestGorm_LeaseLost(t *testing.T) {
+	q := newTestGorm(t, &Config{})
+	id := mustPush(t, q, &mockTask{Name: "a"}, 0)
+	jobs, err := q.reserveBatch(1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+
+	// 租约过期后被其他 worker 领取
+	require.NoError(t, q.db.Model(&SysTask{}).Where("id = ?", id).Update("reserved_by", "other-worker").Error)
+	assert.ErrorIs(t, q.bury(jobs[0], errors.New("boom")), ErrLeaseLost)
+	assert.ErrorIs(t, q.ack(jobs[0]), ErrLeaseLost)
+	assert.ErrorIs(t, q.retry(jobs[0], 0, errors.New("boom")), ErrLeaseLost)
+
+	// 死信写入随事务回滚，任务仍由新 worker 持有
+	var count int64
+	q.db.Model(&SysFailedTask{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	var task SysTask
+	require.NoError(t, q.db.First(&task, id).Error)
+	assert.Equal(t, "other-worker", task.ReservedBy)
+}
+
+func TestGorm_Reap(t *testing.T) {
+	q := newTestGorm(t, &Config{})
+	mustPush(t, q, &mockTask{Name: "a"}, 0)
+
+	jobs, err := q.reserveBatch(1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+
+	// 租约未过期时不回收
+	n, err := q.reap(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	time.Sleep(1100 * time.Millisecond)
+	n, err = q.reap(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	jobs, err = q.reserveBatch(1)
+	require.NoError(t, err)
+	assert.Len(t, jobs, 1)
+}
+
+func TestGorm_ReserveBatch(t *testing.T) {
+	q := newTestGorm(t, &Config{})
+	for i := 0; i < 5; i++ {
+		mustPush(t, q, &mockTask{Name: fmt.Sprint(i)}, 0)
+	}
+	// 未到期的任务不会被取出
+	mustPush(t, q, &mockTask{Name: "later"}, time.Hour)
+
+	jobs, err := q.reserveBatch(3)
+	require.NoError(t, err)
+	assert.Len(t, jobs, 3)
+
+	jobs, err = q.reserveBatch(3)
+	require.NoError(t, err)
+	assert.Len(t, jobs, 2)
+
+	jobs, err = q.reserveBatch(3)
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+}
+
+func TestGorm_QueuesAndPriority(t *testing.T) {
+	q := newTestGorm(t, &Config{Queues: []string{"mail", "default"}, Strict: true})
+
+	mustPush(t, q, &mockTask{Name: "bulk"}, 0, WithQueue("bulk"))
+	mustPush(t, q, &mockTask{Name: "low"}, 0)
+	mustPush(t, q, &mockTask{Name: "high"}, 0, WithPriority(10))
+	mustPush(t, q, &mockTask{Name: "reset"}, 0, WithQueue("mail"))
+
+	jobs, err := q.reserveBatch(10)
+	require.NoError(t, err)
+	// 严格模式下 mail 先于 default，同一队列内高优先级先执行，未监听的 bulk 队列不会被消费
+	assert.Equal(t, []string{"reset", "high", "low"}, jobNames(t, q.worker, jobs))
+}
+
+func TestOrderQueues(t *testing.T) {
+	queues, err := parseQueues([]string{"mail:3", "default"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mail", "default"}, orderQueues(queues, true))
+	assert.ElementsMatch(t, []string{"mail", "default"}, orderQueues(queues, false))
+
+	_, err = parseQueues([]string{"mail:0"})
+	assert.Error(t, err)
+}
+
+// testUnique 各驱动共用的去重测试，wait 用于等待去重窗口过期
+func testUnique(t *testing.T, q IQueue, w *worker, wait func(time.Duration)) {
+	first := mustPush(t, q, &mockTask{Name: "a"}, 0, WithUnique(0))
+	assert.Equal(t, first, mustPush(t, q, &mockTask{Name: "a"}, 0, WithUnique(0)))
+	assert.NotEqual(t, first, mustPush(t, q, &mockTask{Name: "b"}, 0, WithUnique(0)))
+
+	// 指定 key 时忽略任务数据
+	keyed := mustPush(t, q, &mockTask{Name: "c"}, 0, WithUniqueKey("invite:1", 100*time.Millisecond))
+	assert.Equal(t, keyed, mustPush(t, q, &mockTask{Name: "d"}, 0, WithUniqueKey("invite:1", 100*time.Millisecond)))
+
+	jobs, err := w.reserveBatch(10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 3)
+	for _, job := range jobs {
+		require.NoError(t, w.driver.ack(job))
+	}
+
+	// 未设置去重窗口的任务完成后可以再次推送
+	assert.NotEqual(t, first, mustPush(t, q, &mockTask{Name: "a"}, 0, WithUnique(0)))
+
+	// 去重窗口内即使任务已完成也不会重复推送
+	assert.Equal(t, keyed, mustPush(t, q, &mockTask{Name: "c"}, 0, WithUniqueKey("invite:1", 100*time.Millisecond)))
+	wait(150 * time.Millisecond)
+	assert.NotEqual(t, keyed, mustPush(t, q, &mockTask{Name: "c"}, 0, WithUniqueKey("invite:1", 100*time.Millisecond)))
+}
+
+func TestGorm_Unique(t *testing.T) {
+	q := newTestGorm(t, &Config{})
+	testUnique(t, q, q.worker, time.Sleep)
+}
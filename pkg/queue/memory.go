@@ -0,0 +1,453 @@
+package queue
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryHistoryLimit 内存队列保留的最近推送任务、死信任务和已结束批次数，超出后丢弃最旧的记录
+const memoryHistoryLimit = 1000
+
+// memoryJob 内存队列中的任务
+type memoryJob struct {
+	Job
+	task       ITask     // 推送时的原始任务
+	runAt      time.Time // 执行时间
+	reservedAt time.Time // 领取时间
+	unique     string    // 去重标识
+}
+
+// memoryLock 去重锁
+type memoryLock struct {
+	id    uint      // 持有锁的任务 ID
+	until time.Time // 去重窗口截止时间
+	done  bool      // 任务是否已结束
+}
+
+// memoryHeap 任务小顶堆，byPriority 为 true 时先按优先级降序再按执行时间升序，否则只按执行时间升序
+type memoryHeap struct {
+	jobs       []*memoryJob
+	byPriority bool
+}
+
+func (h *memoryHeap) Len() int { return len(h.jobs) }
+func (h *memoryHeap) Less(i, j int) bool {
+	a, b := h.jobs[i], h.jobs[j]
+	if h.byPriority && a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if !a.runAt.Equal(b.runAt) {
+		return a.runAt.Before(b.runAt)
+	}
+	return a.ID < b.ID
+}
+func (h *memoryHeap) Swap(i, j int) { h.jobs[i], h.jobs[j] = h.jobs[j], h.jobs[i] }
+func (h *memoryHeap) Push(x any)    { h.jobs = append(h.jobs, x.(*memoryJob)) }
+func (h *memoryHeap) Pop() any {
+	n := len(h.jobs)
+	job := h.jobs[n-1]
+	h.jobs = h.jobs[:n-1]
+	return job
+}
+
+// ring 固定容量的环形缓冲，写满后覆盖最旧的元素
+type ring[T any] struct {
+	items []T
+	next  int // 写满后下一个覆盖的位置，即最旧元素的位置
+	limit int
+}
+
+func newRing[T any](limit int) *ring[T] {
+	return &ring[T]{limit: limit}
+}
+
+// add 写入元素，写满时返回被覆盖的最旧元素
+func (r *ring[T]) add(v T) (old T, evicted bool) {
+	if len(r.items) < r.limit {
+		r.items = append(r.items, v)
+		return old, false
+	}
+	old = r.items[r.next]
+	r.items[r.next] = v
+	r.next = (r.next + 1) % r.limit
+	return old, true
+}
+
+// list 按写入顺序返回
+func (r *ring[T]) list() []T {
+	items := make([]T, 0, len(r.items))
+	items = append(items, r.items[r.next:]...)
+	return append(items, r.items[:r.next]...)
+}
+
+// memoryQueue 单个命名队列
+type memoryQueue struct {
+	delayed *memoryHeap // 未到期任务，按执行时间排序
+	ready   *memoryHeap // 已到期任务，按优先级和执行时间排序
+}
+
+// Memory 内存队列，适用于测试和单进程部署，进程退出后任务丢失
+type Memory struct {
+	*worker
+	lock     sync.Mutex
+	seq      uint
+	queues   map[string]*memoryQueue
+	reserved map[uint]*memoryJob
+	failed   *ring[*Job]  // 最近的死信任务
+	pushed   *ring[ITask] // 最近推送的任务
+	unique   map[string]*memoryLock
+	batches  map[string]*BatchInfo
+	finished *ring[string] // 最近结束的批次，超出后删除最旧的批次
+}
+
+// NewMemoryQueue 创建内存队列实例
+func NewMemoryQueue(cfg *Config) (IQueue, error) {
+	w, err := newWorker("[MEMORY QUEUE]", cfg)
+	if err != nil {
+		return nil, err
+	}
+	q := &Memory{
+		worker:   w,
+		queues:   make(map[string]*memoryQueue),
+		reserved: make(map[uint]*memoryJob),
+		failed:   newRing[*Job](memoryHistoryLimit),
+		pushed:   newRing[ITask](memoryHistoryLimit),
+		unique:   make(map[string]*memoryLock),
+		batches:  make(map[string]*BatchInfo),
+		finished: newRing[string](memoryHistoryLimit),
+	}
+	w.driver = q
+	w.queue = q
+	return q, nil
+}
+
+// named 获取命名队列，不存在则创建，调用方需持有锁
+func (q *Memory) named(name string) *memoryQueue {
+	mq, ok := q.queues[name]
+	if !ok {
+		mq = &memoryQueue{delayed: &memoryHeap{}, ready: &memoryHeap{byPriority: true}}
+		q.queues[name] = mq
+	}
+	return mq
+}
+
+// Push 推送任务
+func (q *Memory) Push(task ITask, delay time.Duration, opts ...PushOption) (uint, error) {
+	typeName, data, err := encode(task)
+	if err != nil {
+		return 0, err
+	}
+	o := NewPushOptions(opts...)
+	uid := o.uniqueID(typeName, data)
+	now := time.Now()
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if lock, ok := q.unique[uid]; ok && (!lock.done || now.Before(lock.until)) {
+		return lock.id, nil
+	}
+	q.seq++
+	job := &memoryJob{
+		Job: Job{
+			ID:        q.seq,
+			Queue:     o.Queue,
+			Priority:  o.Priority,
+			Type:      typeName,
+			Data:      data,
+			RequestID: o.RequestID,
+			Workflow:  o.workflow,
+		},
+		task:   task,
+		runAt:  now.Add(delay),
+		unique: uid,
+	}
+	if uid != "" {
+		q.unique[uid] = &memoryLock{id: q.seq, until: now.Add(o.UniqueTTL)}
+	}
+	heap.Push(q.named(o.Queue).delayed, job)
+	q.pushed.add(task)
+	metricEnqueued.WithLabelValues(typeName, o.Queue).Inc()
+	return q.seq, nil
+}
+
+// unlock 任务结束后释放去重锁，去重窗口未到期时继续保留，调用方需持有锁
+func (q *Memory) unlock(mj *memoryJob) {
+	lock, ok := q.unique[mj.unique]
+	if !ok || lock.id != mj.ID {
+		return
+	}
+	if time.Now().Before(lock.until) {
+		lock.done = true
+		return
+	}
+	delete(q.unique, mj.unique)
+}
+
+// reserve 领取最多 n 条到期任务，queue 为空表示所有队列
+func (q *Memory) reserve(queue string, n int) ([]*Job, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	var names []string
+	if queue != "" {
+		names = []string{queue}
+	} else {
+		for name := range q.queues {
+			names = append(names, name)
+		}
+	}
+	now := time.Now()
+	var jobs []*Job
+	for _, name := range names {
+		mq := q.named(name)
+		// 到期任务移入 ready
+		for mq.delayed.Len() > 0 && !mq.delayed.jobs[0].runAt.After(now) {
+			heap.Push(mq.ready, heap.Pop(mq.delayed))
+		}
+		for mq.ready.Len() > 0 && len(jobs) < n {
+			job := heap.Pop(mq.ready).(*memoryJob)
+			job.Attempts++
+			job.reservedAt = now
+			q.reserved[job.ID] = job
+			reserved := job.Job
+			reserved.RunAt = job.runAt
+			jobs = append(jobs, &reserved)
+		}
+	}
+	return jobs, nil
+}
+
+// ack 确认任务执行成功并删除
+func (q *Memory) ack(job *Job) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	mj, ok := q.reserved[job.ID]
+	if !ok {
+		return ErrLeaseLost
+	}
+	delete(q.reserved, job.ID)
+	q.unlock(mj)
+	return nil
+}
+
+// retry 放回队列，delay 后再次执行
+func (q *Memory) retry(job *Job, delay time.Duration, execErr error) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	mj, ok := q.reserved[job.ID]
+	if !ok {
+		return ErrLeaseLost
+	}
+	delete(q.reserved, job.ID)
+	mj.ErrorMsg = execErr.Error()
+	mj.runAt = time.Now().Add(delay)
+	heap.Push(q.named(mj.Queue).delayed, mj)
+	return nil
+}
+
+// bury 移入死信
+func (q *Memory) bury(job *Job, execErr error) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	mj, ok := q.reserved[job.ID]
+	if !ok {
+		return ErrLeaseLost
+	}
+	delete(q.reserved, job.ID)
+	q.unlock(mj)
+	failed := *job
+	failed.ErrorMsg = execErr.Error()
+	q.failed.add(&failed)
+	return nil
+}
+
+// touch 续约执行中的任务
+func (q *Memory) touch(job *Job) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if mj, ok := q.reserved[job.ID]; ok {
+		mj.reservedAt = time.Now()
+	}
+	return nil
+}
+
+// reap 将租约过期的任务放回队列
+func (q *Memory) reap(lease time.Duration) (int64, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	expired := time.Now().Add(-lease)
+	var count int64
+	for id, mj := range q.reserved {
+		if mj.reservedAt.Before(expired) {
+			delete(q.reserved, id)
+			heap.Push(q.named(mj.Queue).ready, mj)
+			count++
+		}
+	}
+	// 清理已过期的去重锁
+	now := time.Now()
+	for uid, lock := range q.unique {
+		if lock.done && !now.Before(lock.until) {
+			delete(q.unique, uid)
+		}
+	}
+	return count, nil
+}
+
+// release 将被中断的任务立即放回队列，本次执行不计入执行次数
+func (q *Memory) release(job *Job) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	mj, ok := q.reserved[job.ID]
+	if !ok {
+		return nil
+	}
+	delete(q.reserved, job.ID)
+	mj.Attempts--
+	heap.Push(q.named(mj.Queue).ready, mj)
+	return nil
+}
+
+// depth 按队列和状态统计未完成的任务数
+func (q *Memory) depth() (map[string]map[TaskStatus]int64, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	depth := make(map[string]map[TaskStatus]int64)
+	for name, mq := range q.queues {
+		depth[name] = map[TaskStatus]int64{TaskStatusPending: int64(mq.delayed.Len() + mq.ready.Len())}
+	}
+	for _, mj := range q.reserved {
+		if depth[mj.Queue] == nil {
+			depth[mj.Queue] = make(map[TaskStatus]int64)
+		}
+		depth[mj.Queue][TaskStatusReserved]++
+	}
+	return depth, nil
+}
+
+// Pushed 返回最近推送的任务（按推送顺序，最多 1000 个），便于测试断言
+func (q *Memory) Pushed() []ITask {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.pushed.list()
+}
+
+// Failed 返回最近进入死信的任务（最多 1000 个）
+func (q *Memory) Failed() []*Job {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.failed.list()
+}
+
+// Pending 返回尚未执行完成的任务数（含未到期和执行中的任务）
+func (q *Memory) Pending() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	count := len(q.reserved)
+	for _, mq := range q.queues {
+		count += mq.delayed.Len() + mq.ready.Len()
+	}
+	return count
+}
+
+// Drain 在当前 goroutine 中依次执行所有已到期的任务，直到没有到期任务为止，返回执行的任务数
+//
+//	执行过程中推送的到期任务也会被执行；失败的任务按重试策略延后，不会在本次 Drain 中重复执行
+func (q *Memory) Drain(ctx context.Context) int {
+	count := 0
+	for {
+		jobs, _ := q.reserveBatch(1)
+		if len(jobs) == 0 {
+			return count
+		}
+		q.execute(ctx, jobs[0])
+		count++
+	}
+}
+
+// createBatch 保存新批次
+func (q *Memory) createBatch(b *BatchInfo) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	batch := *b
+	q.batches[b.ID] = &batch
+	return nil
+}
+
+// findBatch 查询批次
+func (q *Memory) findBatch(id string) (*BatchInfo, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	batch, ok := q.batches[id]
+	if !ok {
+		return nil, ErrBatchNotFound
+	}
+	info := *batch
+	return &info, nil
+}
+
+// finishBatchJob 记录批次内一个任务结束
+func (q *Memory) finishBatchJob(id string, succeeded bool) (*BatchInfo, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	batch, ok := q.batches[id]
+	if !ok || batch.Pending <= 0 {
+		return nil, ErrBatchNotFound
+	}
+	batch.Pending--
+	if succeeded {
+		batch.Succeeded++
+	} else {
+		batch.Failed++
+	}
+	if batch.Pending == 0 {
+		now := time.Now()
+		batch.FinishedAt = &now
+		q.finishBatch(id)
+	}
+	info := *batch
+	return &info, nil
+}
+
+// finishBatch 记录已结束的批次，只保留最近的批次，调用方需持有锁
+func (q *Memory) finishBatch(id string) {
+	if old, evicted := q.finished.add(id); evicted {
+		delete(q.batches, old)
+	}
+}
+
+// abortBatch 推送中断时扣除未推送的任务并取消批次
+func (q *Memory) abortBatch(id string, missing int) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	batch, ok := q.batches[id]
+	if !ok {
+		return ErrBatchNotFound
+	}
+	now := time.Now()
+	batch.Total -= missing
+	batch.Pending -= missing
+	if batch.CancelledAt == nil {
+		batch.CancelledAt = &now
+	}
+	if batch.Pending <= 0 && batch.FinishedAt == nil {
+		batch.FinishedAt = &now
+		q.finishBatch(id)
+	}
+	return nil
+}
+
+// cancelBatch 取消批次
+func (q *Memory) cancelBatch(id string) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	batch, ok := q.batches[id]
+	if !ok {
+		return ErrBatchNotFound
+	}
+	if batch.CancelledAt == nil {
+		now := time.Now()
+		batch.CancelledAt = &now
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chainTask 执行时推送下一个任务
+type chainTask struct {
+	Next string `json:"next"`
+}
+
+func (c *chainTask) Execute(ctx context.Context, q IQueue) error {
+	if c.Next == "" {
+		return nil
+	}
+	_, err := q.Push(&mockTask{Name: c.Next}, 0)
+	return err
+}
+
+// failTask 总是执行失败
+type failTask struct{}
+
+func (f *failTask) Execute(ctx context.Context, q IQueue) error {
+	return errors.New("always fail")
+}
+
+func (f *failTask) RetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+func newTestMemory(t *testing.T) *Memory {
+	iq, err := New(&Config{Driver: DriverMemory}, nil, nil)
+	require.NoError(t, err)
+	q := iq.(*Memory)
+	require.NoError(t, q.Register(&mockTask{}))
+	require.NoError(t, q.Register(&chainTask{}))
+	require.NoError(t, q.Register(&failTask{}))
+	return q
+}
+
+func TestMemory_Drain(t *testing.T) {
+	q := newTestMemory(t)
+	mustPush(t, q, &chainTask{Next: "b"}, 0)
+	mustPush(t, q, &mockTask{Name: "later"}, time.Hour)
+	mustPush(t, q, &failTask{}, 0)
+
+	// chainTask 执行时推送的任务也会被执行，未到期的任务保留
+	assert.Equal(t, 3, q.Drain(context.Background()))
+	assert.Equal(t, 1, q.Pending())
+
+	pushed := q.Pushed()
+	require.Len(t, pushed, 4)
+	assert.Equal(t, &mockTask{Name: "b"}, pushed[3])
+
+	failed := q.Failed()
+	require.Len(t, failed, 1)
+	assert.Equal(t, "always fail", failed[0].ErrorMsg)
+}
+
+func TestMemory_HistoryLimit(t *testing.T) {
+	q := newTestMemory(t)
+	q.pushed = newRing[ITask](3)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		mustPush(t, q, &mockTask{Name: name}, time.Hour)
+	}
+	// 只保留最近的记录
+	assert.Equal(t, []ITask{&mockTask{Name: "c"}, &mockTask{Name: "d"}, &mockTask{Name: "e"}}, q.Pushed())
+}
+
+func TestMemory_BatchLimit(t *testing.T) {
+	q := newTestMemory(t)
+	q.finished = newRing[string](2)
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, q.createBatch(&BatchInfo{ID: id, Total: 1, Pending: 1}))
+	}
+	require.NoError(t, q.createBatch(&BatchInfo{ID: "running", Total: 1, Pending: 1}))
+	for _, id := range []string{"a", "b", "c"} {
+		_, err := q.finishBatchJob(id, true)
+		require.NoError(t, err)
+	}
+	// 只保留最近结束的批次，未结束的批次不受影响
+	_, err := q.findBatch("a")
+	assert.ErrorIs(t, err, ErrBatchNotFound)
+	for _, id := range []string{"b", "c", "running"} {
+		_, err := q.findBatch(id)
+		assert.NoError(t, err, id)
+	}
+}
+
+func TestMemory_QueuesAndPriority(t *testing.T) {
+	iq, err := NewMemoryQueue(&Config{Queues: []string{"mail", "default"}, Strict: true})
+	require.NoError(t, err)
+	q := iq.(*Memory)
+	require.NoError(t, q.Register(&mockTask{}))
+
+	mustPush(t, q, &mockTask{Name: "bulk"}, 0, WithQueue("bulk"))
+	mustPush(t, q, &mockTask{Name: "low"}, 0)
+	mustPush(t, q, &mockTask{Name: "high"}, 0, WithPriority(10))
+	mustPush(t, q, &mockTask{Name: "reset"}, 0, WithQueue("mail"))
+
+	jobs, err := q.reserveBatch(10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"reset", "high", "low"}, jobNames(t, q.worker, jobs))
+}
+
+func TestMemory_Unique(t *testing.T) {
+	q := newTestMemory(t)
+	testUnique(t, q, q.worker, time.Sleep)
+}
@@ -0,0 +1,98 @@
+package queue
+
+import (
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"log/slog"
+	"time"
+)
+
+var (
+	metricEnqueued = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "queue_jobs_enqueued_total",
+		Help: "推送到队列的任务数",
+	}, []string{"type", "queue"})
+	metricProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "queue_jobs_processed_total",
+		Help: "执行成功的任务数",
+	}, []string{"type", "queue"})
+	metricFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "queue_jobs_failed_total",
+		Help: "重试耗尽进入死信的任务数",
+	}, []string{"type", "queue"})
+	metricRetried = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "queue_jobs_retried_total",
+		Help: "执行失败后放回队列重试的任务数",
+	}, []string{"type", "queue"})
+	metricWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "queue_job_wait_seconds",
+		Help:    "任务从到期到开始执行的等待时间",
+		Buckets: prometheus.ExponentialBuckets(0.01, 4, 10),
+	}, []string{"type", "queue"})
+	metricDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "queue_job_duration_seconds",
+		Help:    "任务执行耗时",
+		Buckets: prometheus.ExponentialBuckets(0.005, 4, 10),
+	}, []string{"type", "queue"})
+)
+
+// depthReporter 由各驱动实现，返回各队列按状态统计的任务数
+type depthReporter interface {
+	depth() (map[string]map[TaskStatus]int64, error)
+}
+
+// depthCollector 在采集时统计队列深度
+type depthCollector struct {
+	reporter depthReporter
+	desc     *prometheus.Desc
+}
+
+// NewDepthCollector 创建队列深度采集器，采集时按队列和状态统计未完成的任务数
+func NewDepthCollector(q IQueue) (prometheus.Collector, error) {
+	reporter, ok := q.(depthReporter)
+	if !ok {
+		return nil, errors.New("queue driver does not support depth metrics")
+	}
+	return &depthCollector{
+		reporter: reporter,
+		desc:     prometheus.NewDesc("queue_depth", "队列中未完成的任务数", []string{"queue", "status"}, nil),
+	}, nil
+}
+
+// RegisterDepthCollector 注册队列深度采集器，驱动不支持或已注册时忽略
+func RegisterDepthCollector(q IQueue) error {
+	collector, err := NewDepthCollector(q)
+	if err != nil {
+		return nil
+	}
+	var already prometheus.AlreadyRegisteredError
+	if err := prometheus.Register(collector); err != nil && !errors.As(err, &already) {
+		return err
+	}
+	return nil
+}
+
+func (c *depthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *depthCollector) Collect(ch chan<- prometheus.Metric) {
+	depth, err := c.reporter.depth()
+	if err != nil {
+		slog.Warn("[QUEUE] collect depth error", slog.Any("err", err))
+		return
+	}
+	for queue, statuses := range depth {
+		for status, count := range statuses {
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), queue, string(status))
+		}
+	}
+}
+
+// observeStart 记录任务等待时间
+func observeStart(job *Job) {
+	if !job.RunAt.IsZero() {
+		metricWait.WithLabelValues(job.Type, job.Queue).Observe(max(time.Since(job.RunAt), 0).Seconds())
+	}
+}
@@ -0,0 +1,40 @@
+package queue
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	q := newTestMemory(t)
+	typeName, err := GetTaskTypeName(&failTask{})
+	require.NoError(t, err)
+	mockType, err := GetTaskTypeName(&mockTask{})
+	require.NoError(t, err)
+	enqueued := testutil.ToFloat64(metricEnqueued.WithLabelValues(mockType, "metrics"))
+	processed := testutil.ToFloat64(metricProcessed.WithLabelValues(mockType, "metrics"))
+	failed := testutil.ToFloat64(metricFailed.WithLabelValues(typeName, "metrics"))
+
+	mustPush(t, q, &mockTask{Name: "a"}, 0, WithQueue("metrics"))
+	mustPush(t, q, &failTask{}, 0, WithQueue("metrics"))
+	mustPush(t, q, &mockTask{Name: "later"}, time.Hour, WithQueue("metrics"))
+
+	collector, err := NewDepthCollector(q)
+	require.NoError(t, err)
+	require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP queue_depth 队列中未完成的任务数
+# TYPE queue_depth gauge
+queue_depth{queue="metrics",status="pending"} 3
+`)))
+
+	q.Drain(context.Background())
+	assert.Equal(t, enqueued+2, testutil.ToFloat64(metricEnqueued.WithLabelValues(mockType, "metrics")))
+	assert.Equal(t, processed+1, testutil.ToFloat64(metricProcessed.WithLabelValues(mockType, "metrics")))
+	assert.Equal(t, failed+1, testutil.ToFloat64(metricFailed.WithLabelValues(typeName, "metrics")))
+}
@@ -0,0 +1,100 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+	"wangzhiqiang/skeleton/pkg/logger"
+)
+
+// Handler 任务执行函数
+type Handler func(ctx context.Context, job *Job, task ITask) error
+
+// Middleware 任务中间件，按注册顺序由外到内包裹任务执行
+type Middleware func(next Handler) Handler
+
+// ITimeoutTask 可选接口，任务实现后可自定义执行超时
+type ITimeoutTask interface {
+	Timeout() time.Duration
+}
+
+// chain 将中间件依次包裹在 handler 外层
+func chain(handler Handler, mws []Middleware) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		handler = mws[i](handler)
+	}
+	return handler
+}
+
+// Recovery 捕获任务执行中的 panic 并转换为错误，任务按重试策略处理
+func Recovery() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, job *Job, task ITask) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					slog.Error("[QUEUE] task panic", slog.Any("panic", r), slog.String("type", job.Type), slog.String("stack", string(debug.Stack())))
+					err = fmt.Errorf("task panic: %v", r)
+				}
+			}()
+			return next(ctx, job, task)
+		}
+	}
+}
+
+// Logging 记录任务执行结果和耗时
+func Logging(log logger.ILogger) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, job *Job, task ITask) error {
+			start := time.Now()
+			err := next(ctx, job, task)
+			if err != nil {
+				log.Warnf("[QUEUE] task failed id: %d type: %s queue: %s attempts: %d request_id: %s cost: %v err: %v",
+					job.ID, job.Type, job.Queue, job.Attempts, job.RequestID, time.Since(start), err)
+				return err
+			}
+			log.Infof("[QUEUE] task done id: %d type: %s queue: %s attempts: %d request_id: %s cost: %v",
+				job.ID, job.Type, job.Queue, job.Attempts, job.RequestID, time.Since(start))
+			return nil
+		}
+	}
+}
+
+// Timeout 限制任务执行时间，任务实现 ITimeoutTask 时使用任务自身的超时，d <= 0 表示默认不限制
+func Timeout(d time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, job *Job, task ITask) error {
+			timeout := d
+			if t, ok := task.(ITimeoutTask); ok {
+				timeout = t.Timeout()
+			}
+			if timeout <= 0 {
+				return next(ctx, job, task)
+			}
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return next(ctx, job, task)
+		}
+	}
+}
+
+type requestIDKey struct{}
+
+// RequestID 将推送任务时的请求 ID 写入 context，任务中通过 GetRequestID 获取
+func RequestID() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, job *Job, task ITask) error {
+			if job.RequestID != "" {
+				ctx = context.WithValue(ctx, requestIDKey{}, job.RequestID)
+			}
+			return next(ctx, job, task)
+		}
+	}
+}
+
+// GetRequestID 获取推送任务时的请求 ID
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,79 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// panicTask 执行时 panic
+type panicTask struct{}
+
+func (p *panicTask) Execute(ctx context.Context, q IQueue) error {
+	panic("boom")
+}
+
+func (p *panicTask) RetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+// ctxTask 记录执行时的 context
+type ctxTask struct{}
+
+var lastCtx context.Context
+
+func (c *ctxTask) Execute(ctx context.Context, q IQueue) error {
+	lastCtx = ctx
+	return nil
+}
+
+func (c *ctxTask) Timeout() time.Duration {
+	return time.Minute
+}
+
+func TestMiddleware_Order(t *testing.T) {
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, job *Job, task ITask) error {
+				calls = append(calls, name+":before")
+				err := next(ctx, job, task)
+				calls = append(calls, name+":after")
+				return err
+			}
+		}
+	}
+	q := newTestMemory(t)
+	q.Use(record("a"), record("b"))
+	mustPush(t, q, &mockTask{Name: "a"}, 0)
+	assert.Equal(t, 1, q.Drain(context.Background()))
+	assert.Equal(t, []string{"a:before", "b:before", "b:after", "a:after"}, calls)
+}
+
+func TestMiddleware_Recovery(t *testing.T) {
+	q := newTestMemory(t)
+	require.NoError(t, q.Register(&panicTask{}))
+	q.Use(Recovery())
+	mustPush(t, q, &panicTask{}, 0)
+	assert.Equal(t, 1, q.Drain(context.Background()))
+	failed := q.Failed()
+	require.Len(t, failed, 1)
+	assert.Equal(t, "task panic: boom", failed[0].ErrorMsg)
+}
+
+func TestMiddleware_TimeoutAndRequestID(t *testing.T) {
+	q := newTestMemory(t)
+	require.NoError(t, q.Register(&ctxTask{}))
+	q.Use(RequestID(), Timeout(time.Second))
+	mustPush(t, q, &ctxTask{}, 0, WithRequestID("req-1"))
+	assert.Equal(t, 1, q.Drain(context.Background()))
+	require.NotNil(t, lastCtx)
+	assert.Equal(t, "req-1", GetRequestID(lastCtx))
+	deadline, ok := lastCtx.Deadline()
+	require.True(t, ok)
+	// 任务自身的超时优先于默认超时
+	assert.Greater(t, time.Until(deadline), time.Second)
+}
@@ -0,0 +1,153 @@
+package queue
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultQueue = "default" // 默认队列名
+)
+
+// PushOptions 推送任务的可选参数
+type PushOptions struct {
+	Queue     string        // 队列名
+	Priority  int           // 优先级，数值越大越先执行
+	Unique    bool          // 是否去重
+	UniqueKey string        // 去重 key，为空时使用任务数据
+	UniqueTTL time.Duration // 去重窗口，从推送时开始计算，0 表示只在任务完成前去重
+	RequestID string        // 推送任务的请求 ID，执行时通过 GetRequestID 获取
+	workflow  string        // 链式、批量任务的流程信息
+}
+
+// PushOption 推送任务选项
+type PushOption func(*PushOptions)
+
+// WithQueue 指定任务投递的队列
+func WithQueue(name string) PushOption {
+	return func(o *PushOptions) {
+		o.Queue = name
+	}
+}
+
+// WithPriority 指定任务在队列内的优先级，数值越大越先执行
+func WithPriority(priority int) PushOption {
+	return func(o *PushOptions) {
+		o.Priority = priority
+	}
+}
+
+// WithRequestID 记录推送任务的请求 ID，便于串联 HTTP 请求和任务日志
+func WithRequestID(id string) PushOption {
+	return func(o *PushOptions) {
+		o.RequestID = id
+	}
+}
+
+// WithUnique 按任务类型和数据去重
+//
+//	ttl 内或任务完成前重复推送相同的任务不会生成新任务，而是返回已有任务的 ID
+func WithUnique(ttl time.Duration) PushOption {
+	return func(o *PushOptions) {
+		o.Unique = true
+		o.UniqueTTL = ttl
+	}
+}
+
+// WithUniqueKey 按任务类型和指定的 key 去重，规则同 WithUnique
+func WithUniqueKey(key string, ttl time.Duration) PushOption {
+	return func(o *PushOptions) {
+		o.Unique = true
+		o.UniqueKey = key
+		o.UniqueTTL = ttl
+	}
+}
+
+// uniqueID 计算任务的去重标识，未开启去重时返回空
+func (o *PushOptions) uniqueID(typeName, data string) string {
+	if !o.Unique {
+		return ""
+	}
+	key := o.UniqueKey
+	if key == "" {
+		key = data
+	}
+	sum := sha256.Sum256([]byte(typeName + "\n" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewPushOptions 合并推送选项
+func NewPushOptions(opts ...PushOption) *PushOptions {
+	o := &PushOptions{Queue: DefaultQueue}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Queue == "" {
+		o.Queue = DefaultQueue
+	}
+	return o
+}
+
+// queueWeight 消费的队列及其权重
+type queueWeight struct {
+	name   string
+	weight int
+}
+
+// parseQueues 解析要消费的队列列表，格式为 name 或 name:weight，权重默认为 1
+func parseQueues(list []string) ([]queueWeight, error) {
+	var queues []queueWeight
+	for _, item := range list {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, weightStr, found := strings.Cut(item, ":")
+		weight := 1
+		if found {
+			w, err := strconv.Atoi(weightStr)
+			if err != nil || w <= 0 {
+				return nil, fmt.Errorf("invalid queue weight: %s", item)
+			}
+			weight = w
+		}
+		queues = append(queues, queueWeight{name: strings.TrimSpace(name), weight: weight})
+	}
+	return queues, nil
+}
+
+// orderQueues 返回本次拉取时各队列的先后顺序
+//
+//	strict 为 true 时按配置顺序，排在前面的队列有任务就先执行；
+//	否则按权重随机排序，权重越大越可能排在前面
+func orderQueues(queues []queueWeight, strict bool) []string {
+	names := make([]string, 0, len(queues))
+	if strict {
+		for _, q := range queues {
+			names = append(names, q.name)
+		}
+		return names
+	}
+	rest := append([]queueWeight(nil), queues...)
+	for len(rest) > 0 {
+		total := 0
+		for _, q := range rest {
+			total += q.weight
+		}
+		r := rand.IntN(total)
+		for i, q := range rest {
+			if r < q.weight {
+				names = append(names, q.name)
+				rest = append(rest[:i], rest[i+1:]...)
+				break
+			}
+			r -= q.weight
+		}
+	}
+	return names
+}
@@ -2,33 +2,116 @@ package queue
 
 import (
 	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 	"time"
 	"wangzhiqiang/skeleton/pkg/database"
+	"wangzhiqiang/skeleton/pkg/redisx"
 )
 
 type IQueue interface {
 	// Register 注册任务，比如初始化或者将任务写入某个存储（DB、Redis、内存等）
 	Register(task ITask) error
 
-	// Push 推送任务，可以带延时（适合定时任务、延迟队列）
-	Push(task ITask, delay time.Duration) error
+	// Push 推送任务，可以带延时（适合定时任务、延迟队列），opts 可指定队列、优先级和去重，返回任务 ID
+	//
+	//	去重命中时不会生成新任务，返回已有任务的 ID
+	Push(task ITask, delay time.Duration, opts ...PushOption) (uint, error)
 
-	// Start 启动队列监听，interval 表示检查/拉取任务的间隔
+	// Use 注册任务中间件，用于异常恢复、日志、超时等，需在 Start 前调用
+	Use(mws ...Middleware)
+
+	// Start 启动队列监听，interval 表示空闲时检查/拉取任务的最短间隔
 	Start(ctx context.Context, interval time.Duration)
 
-	// Stop 停止队列
+	// Stop 停止队列，等待执行中的任务完成，超时未完成的任务会被取消并放回队列
 	Stop()
 }
 
+const (
+	DriverDatabase = "database" // 数据库驱动（默认）
+	DriverRedis    = "redis"    // Redis 驱动
+	DriverMemory   = "memory"   // 内存驱动，适用于测试和单进程部署
+)
+
+const (
+	DefaultLeaseTimeout    = 60   // 默认任务租约超时（秒）
+	DefaultWorkers         = 10   // 默认并发 worker 数
+	DefaultBatchSize       = 10   // 默认每次拉取任务数
+	DefaultPollInterval    = 1000 // 默认最短拉取间隔（毫秒）
+	DefaultMaxPollInterval = 5000 // 默认空闲时最长拉取间隔（毫秒）
+	DefaultDrainTimeout    = 5    // 默认停止时等待执行中任务的时间（秒）
+)
+
 type Config struct {
-	DB *database.Config `yaml:"db" json:"db,omitempty"`
+	Driver          string           `yaml:"driver" json:"driver,omitempty"`                       // 队列驱动：database、redis、memory，默认 database
+	DB              *database.Config `yaml:"db" json:"db,omitempty"`                               // database 驱动单独使用的数据库，为空时使用全局数据库
+	Redis           *redisx.Config   `yaml:"redis" json:"redis,omitempty"`                         // redis 驱动单独使用的 Redis，为空时使用全局 Redis
+	Prefix          string           `yaml:"prefix" json:"prefix,omitempty"`                       // redis 驱动的 key 前缀，默认 {queue}:，未带 hash tag 时自动包裹以保证同一 slot
+	LeaseTimeout    int              `yaml:"lease_timeout" json:"lease_timeout,omitempty"`         // 任务租约超时（秒），超时未确认的任务会被重新投递
+	Workers         int              `yaml:"workers" json:"workers,omitempty"`                     // 并发执行任务的 worker 数
+	BatchSize       int              `yaml:"batch_size" json:"batch_size,omitempty"`               // 每次拉取的最大任务数
+	PollInterval    int              `yaml:"poll_interval" json:"poll_interval,omitempty"`         // 最短拉取间隔（毫秒），有任务时立即拉取，无任务时从该值开始退避
+	MaxPollInterval int              `yaml:"max_poll_interval" json:"max_poll_interval,omitempty"` // 空闲时最长拉取间隔（毫秒）
+	Queues          []string         `yaml:"queues" json:"queues,omitempty"`                       // 要消费的队列，格式 name 或 name:weight，为空时消费所有队列
+	Strict          bool             `yaml:"strict" json:"strict,omitempty"`                       // 严格按 Queues 顺序消费，否则按权重随机
+	Timeout         int              `yaml:"timeout" json:"timeout,omitempty"`                     // 任务默认执行超时（秒），0 表示不限制
+	DrainTimeout    int              `yaml:"drain_timeout" json:"drain_timeout,omitempty"`         // 停止时等待执行中任务的时间（秒），超时后取消任务并放回队列
+}
+
+// GetPollInterval 返回最短拉取间隔
+func (c *Config) GetPollInterval() time.Duration {
+	return time.Duration(c.PollInterval) * time.Millisecond
+}
+
+// GetTimeout 返回任务默认执行超时
+func (c *Config) GetTimeout() time.Duration {
+	return time.Duration(c.Timeout) * time.Second
+}
+
+// GetMaxPollInterval 返回空闲时最长拉取间隔
+func (c *Config) GetMaxPollInterval() time.Duration {
+	return time.Duration(c.MaxPollInterval) * time.Millisecond
 }
 
-func New(cfg *Config, db *gorm.DB) (IQueue, error) {
-	var err error
-	if cfg.DB != nil {
-		db, err = database.Init(cfg.DB)
+// New 根据配置创建队列，db 和 rdb 为全局的数据库和 Redis 客户端
+func New(cfg *Config, db *gorm.DB, rdb redis.UniversalClient) (IQueue, error) {
+	if cfg.LeaseTimeout <= 0 {
+		cfg.LeaseTimeout = DefaultLeaseTimeout
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultWorkers
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultPollInterval
+	}
+	if cfg.MaxPollInterval < cfg.PollInterval {
+		cfg.MaxPollInterval = max(DefaultMaxPollInterval, cfg.PollInterval)
+	}
+	switch cfg.Driver {
+	case "", DriverDatabase:
+		if cfg.DB != nil {
+			var err error
+			if db, err = database.Init(cfg.DB); err != nil {
+				return nil, err
+			}
+		}
+		return NewGormQueue(db, cfg)
+	case DriverRedis:
+		if cfg.Redis != nil {
+			rdb = redisx.New(cfg.Redis)
+		}
+		if rdb == nil {
+			return nil, fmt.Errorf("queue driver redis requires redis config")
+		}
+		return NewRedisQueue(rdb, cfg)
+	case DriverMemory:
+		return NewMemoryQueue(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported queue driver: %s", cfg.Driver)
 	}
-	return NewGormQueue(db), err
 }
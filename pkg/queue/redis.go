@@ -0,0 +1,552 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultRedisPrefix = "{queue}:" // 默认 Redis key 前缀，带 hash tag
+
+	// redisFailedLimit 保留的死信任务数，超出后删除最旧的死信和任务数据
+	redisFailedLimit = 10000
+)
+
+// Redis 队列键说明（prefix 默认为 {queue}:）：
+//
+//	prefix 必须带 hash tag（例如 {queue}:），保证所有 key 在 Redis Cluster 的同一个 slot，
+//	脚本中按任务 ID 拼接的 job key 无法提前声明在 KEYS 中，依赖同一 slot 才能在集群中执行；
+//	未带 hash tag 的 prefix 会自动加上，例如 app:queue: 变为 {app:queue}:
+//
+//	{prefix}queues            SET   所有出现过的队列名
+//	{prefix}id                STRING 任务 ID 自增计数
+//	{prefix}job:{id}          HASH  任务数据、执行次数、领取信息
+//	{prefix}{queue}:delayed   ZSET  未到期任务，score 为执行时间（毫秒）
+//	{prefix}{queue}:ready     ZSET  已到期任务，score 按优先级和执行时间排序
+//	{prefix}{queue}:reserved  LIST  已领取、执行中的任务
+//	{prefix}failed            LIST  死信任务，只保留最近的 redisFailedLimit 条
+//	{prefix}unique:{uid}      STRING 去重锁，值为持有锁的任务 ID
+//	{prefix}batch:{id}        HASH  批次信息和计数
+var (
+	// 推送任务：获取去重锁后写入任务数据，锁已被其他任务持有时返回该任务 ID
+	// KEYS: job, queues, delayed[, unique]  ARGV: id, queue, run_at, fields...
+	redisPushScript = redis.NewScript(`
+if KEYS[4] and not redis.call('SET', KEYS[4], ARGV[1], 'NX') then
+	return redis.call('GET', KEYS[4])
+end
+redis.call('HSET', KEYS[1], unpack(ARGV, 4))
+redis.call('SADD', KEYS[2], ARGV[2])
+redis.call('ZADD', KEYS[3], ARGV[3], ARGV[1])
+return ARGV[1]
+`)
+
+	// 将到期任务移入 ready，再按顺序领取最多 n 条
+	// KEYS: delayed, ready, reserved  ARGV: now, n, worker, prefix
+	redisReserveScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'WITHSCORES', 'LIMIT', 0, 1000)
+for i = 1, #due, 2 do
+	local id = due[i]
+	local prio = tonumber(redis.call('HGET', ARGV[4] .. 'job:' .. id, 'priority') or '0')
+	redis.call('ZREM', KEYS[1], id)
+	redis.call('ZADD', KEYS[2], -prio * 1e13 + tonumber(due[i + 1]), id)
+end
+local popped = redis.call('ZPOPMIN', KEYS[2], ARGV[2])
+local ids = {}
+for i = 1, #popped, 2 do
+	local id = popped[i]
+	local key = ARGV[4] .. 'job:' .. id
+	redis.call('RPUSH', KEYS[3], id)
+	redis.call('HSET', key, 'reserved_at', ARGV[1], 'reserved_by', ARGV[3])
+	redis.call('HINCRBY', key, 'attempts', 1)
+	table.insert(ids, id)
+end
+return ids
+`)
+
+	// 确认任务：从 reserved 移除、释放去重锁并删除任务数据
+	// KEYS: reserved, job  ARGV: id, worker, now
+	redisAckScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], 'reserved_by') ~= ARGV[2] then return 0 end
+redis.call('LREM', KEYS[1], 1, ARGV[1])
+local lock = redis.call('HGET', KEYS[2], 'unique')
+if lock and redis.call('GET', lock) == ARGV[1] then
+	local untilMs = tonumber(redis.call('HGET', KEYS[2], 'unique_until') or '0')
+	if untilMs > tonumber(ARGV[3]) then
+		redis.call('PEXPIREAT', lock, untilMs)
+	else
+		redis.call('DEL', lock)
+	end
+end
+redis.call('DEL', KEYS[2])
+return 1
+`)
+
+	// 重试任务：从 reserved 移回 delayed
+	// KEYS: reserved, job, delayed  ARGV: id, worker, run_at, error
+	redisRetryScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], 'reserved_by') ~= ARGV[2] then return 0 end
+redis.call('LREM', KEYS[1], 1, ARGV[1])
+redis.call('HDEL', KEYS[2], 'reserved_at', 'reserved_by')
+redis.call('HSET', KEYS[2], 'error', ARGV[4], 'run_at', ARGV[3])
+redis.call('ZADD', KEYS[3], ARGV[3], ARGV[1])
+return 1
+`)
+
+	// 移入死信：从 reserved 移到 failed 并释放去重锁，超出数量限制时删除最旧的死信
+	// KEYS: reserved, job, failed  ARGV: id, error, failed_at, worker, limit, prefix
+	redisBuryScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], 'reserved_by') ~= ARGV[4] then return 0 end
+redis.call('LREM', KEYS[1], 1, ARGV[1])
+local lock = redis.call('HGET', KEYS[2], 'unique')
+if lock and redis.call('GET', lock) == ARGV[1] then
+	local untilMs = tonumber(redis.call('HGET', KEYS[2], 'unique_until') or '0')
+	if untilMs > tonumber(ARGV[3]) then
+		redis.call('PEXPIREAT', lock, untilMs)
+	else
+		redis.call('DEL', lock)
+	end
+end
+redis.call('HDEL', KEYS[2], 'reserved_at', 'reserved_by')
+redis.call('HSET', KEYS[2], 'error', ARGV[2], 'failed_at', ARGV[3])
+redis.call('RPUSH', KEYS[3], ARGV[1])
+local over = redis.call('LLEN', KEYS[3]) - tonumber(ARGV[5])
+for i = 1, over do
+	redis.call('DEL', ARGV[6] .. 'job:' .. redis.call('LPOP', KEYS[3]))
+end
+return 1
+`)
+
+	// 续约任务
+	// KEYS: job  ARGV: worker, now
+	redisTouchScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'reserved_by') ~= ARGV[1] then return 0 end
+redis.call('HSET', KEYS[1], 'reserved_at', ARGV[2])
+return 1
+`)
+
+	// 放回被中断的任务：从 reserved 移回 ready，本次执行不计入次数
+	// KEYS: reserved, job, ready  ARGV: id, worker, now
+	redisReleaseScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], 'reserved_by') ~= ARGV[2] then return 0 end
+local prio = tonumber(redis.call('HGET', KEYS[2], 'priority') or '0')
+redis.call('LREM', KEYS[1], 1, ARGV[1])
+redis.call('HDEL', KEYS[2], 'reserved_at', 'reserved_by')
+redis.call('HINCRBY', KEYS[2], 'attempts', -1)
+redis.call('ZADD', KEYS[3], -prio * 1e13 + tonumber(ARGV[3]), ARGV[1])
+return 1
+`)
+
+	// 批次内一个任务结束：更新计数，全部结束时记录结束时间
+	// KEYS: batch  ARGV: counter, now
+	redisBatchScript = redis.NewScript(`
+local pending = tonumber(redis.call('HGET', KEYS[1], 'pending') or '0')
+if pending <= 0 then return {} end
+pending = redis.call('HINCRBY', KEYS[1], 'pending', -1)
+redis.call('HINCRBY', KEYS[1], ARGV[1], 1)
+if pending == 0 then redis.call('HSET', KEYS[1], 'finished_at', ARGV[2]) end
+return redis.call('HGETALL', KEYS[1])
+`)
+
+	// 推送中断时扣除未推送的任务并取消批次
+	// KEYS: batch  ARGV: missing, now
+	redisAbortBatchScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then return 0 end
+redis.call('HINCRBY', KEYS[1], 'total', -tonumber(ARGV[1]))
+local pending = redis.call('HINCRBY', KEYS[1], 'pending', -tonumber(ARGV[1]))
+redis.call('HSETNX', KEYS[1], 'cancelled_at', ARGV[2])
+if pending <= 0 then redis.call('HSETNX', KEYS[1], 'finished_at', ARGV[2]) end
+return 1
+`)
+
+	// 回收租约过期的任务，放回 ready
+	// KEYS: reserved, ready  ARGV: expired_before, now, prefix
+	redisReapScript = redis.NewScript(`
+local ids = redis.call('LRANGE', KEYS[1], 0, -1)
+local count = 0
+for _, id in ipairs(ids) do
+	local key = ARGV[3] .. 'job:' .. id
+	local at = tonumber(redis.call('HGET', key, 'reserved_at') or '0')
+	if at < tonumber(ARGV[1]) then
+		local prio = tonumber(redis.call('HGET', key, 'priority') or '0')
+		redis.call('LREM', KEYS[1], 1, id)
+		redis.call('HDEL', key, 'reserved_at', 'reserved_by')
+		redis.call('ZADD', KEYS[2], -prio * 1e13 + tonumber(ARGV[2]), id)
+		count = count + 1
+	end
+end
+return count
+`)
+)
+
+type Redis struct {
+	*worker
+	rdb         redis.UniversalClient
+	prefix      string
+	failedLimit int // 保留的死信任务数
+}
+
+// NewRedisQueue 创建 Redis 队列实例
+func NewRedisQueue(rdb redis.UniversalClient, cfg *Config) (IQueue, error) {
+	w, err := newWorker("[REDIS QUEUE]", cfg)
+	if err != nil {
+		return nil, err
+	}
+	prefix := cfg.Prefix
+	if prefix == "" {
+		prefix = DefaultRedisPrefix
+	}
+	if !hasHashTag(prefix) {
+		prefix = "{" + strings.TrimSuffix(prefix, ":") + "}:"
+	}
+	q := &Redis{worker: w, rdb: rdb, prefix: prefix, failedLimit: redisFailedLimit}
+	w.driver = q
+	w.queue = q
+	return q, nil
+}
+
+// hasHashTag prefix 是否包含非空的 hash tag
+func hasHashTag(prefix string) bool {
+	start := strings.Index(prefix, "{")
+	if start < 0 {
+		return false
+	}
+	end := strings.Index(prefix[start+1:], "}")
+	return end > 0
+}
+
+func (q *Redis) key(parts ...string) string {
+	key := q.prefix
+	for i, part := range parts {
+		if i > 0 {
+			key += ":"
+		}
+		key += part
+	}
+	return key
+}
+
+func (q *Redis) jobKey(id uint) string {
+	return q.key("job", strconv.FormatUint(uint64(id), 10))
+}
+
+// Push 推送任务
+func (q *Redis) Push(task ITask, delay time.Duration, opts ...PushOption) (uint, error) {
+	typeName, data, err := encode(task)
+	if err != nil {
+		return 0, err
+	}
+	o := NewPushOptions(opts...)
+	ctx := context.Background()
+	id, err := q.rdb.Incr(ctx, q.key("id")).Result()
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	runAt := now.Add(delay).UnixMilli()
+	fields := []any{
+		"queue", o.Queue,
+		"priority", o.Priority,
+		"type", typeName,
+		"data", data,
+		"attempts", 0,
+		"created_at", now.UnixMilli(),
+		"run_at", runAt,
+	}
+	if o.RequestID != "" {
+		fields = append(fields, "request_id", o.RequestID)
+	}
+	if o.workflow != "" {
+		fields = append(fields, "workflow", o.workflow)
+	}
+	keys := []string{q.jobKey(uint(id)), q.key("queues"), q.key(o.Queue, "delayed")}
+	if uid := o.uniqueID(typeName, data); uid != "" {
+		// 去重锁在任务结束前不过期，结束时再按去重窗口设置过期时间
+		lock := q.key("unique", uid)
+		keys = append(keys, lock)
+		fields = append(fields, "unique", lock, "unique_until", now.Add(o.UniqueTTL).UnixMilli())
+	}
+	// 去重锁和任务数据在同一个脚本中写入，避免写入失败后锁无法释放
+	args := append([]any{id, o.Queue, runAt}, fields...)
+	got, err := redisPushScript.Run(ctx, q.rdb, keys, args...).Uint64()
+	if err != nil {
+		return 0, err
+	}
+	if got != uint64(id) {
+		return uint(got), nil
+	}
+	metricEnqueued.WithLabelValues(typeName, o.Queue).Inc()
+	return uint(id), nil
+}
+
+// queueNames 返回所有出现过的队列名
+func (q *Redis) queueNames() ([]string, error) {
+	return q.rdb.SMembers(context.Background(), q.key("queues")).Result()
+}
+
+// reserve 领取最多 n 条到期任务，queue 为空表示所有队列
+func (q *Redis) reserve(queue string, n int) ([]*Job, error) {
+	names := []string{queue}
+	if queue == "" {
+		var err error
+		if names, err = q.queueNames(); err != nil {
+			return nil, err
+		}
+	}
+	ctx := context.Background()
+	var jobs []*Job
+	for _, name := range names {
+		if len(jobs) >= n {
+			break
+		}
+		ids, err := redisReserveScript.Run(ctx, q.rdb,
+			[]string{q.key(name, "delayed"), q.key(name, "ready"), q.key(name, "reserved")},
+			time.Now().UnixMilli(), n-len(jobs), q.id, q.prefix,
+		).StringSlice()
+		if err != nil {
+			return jobs, err
+		}
+		for _, id := range ids {
+			job, err := q.load(id)
+			if err != nil {
+				return jobs, err
+			}
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// load 读取任务数据
+func (q *Redis) load(id string) (*Job, error) {
+	values, err := q.rdb.HGetAll(context.Background(), q.key("job", id)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("redis queue job %s not found", id)
+	}
+	jobID, _ := strconv.ParseUint(id, 10, 64)
+	priority, _ := strconv.Atoi(values["priority"])
+	attempts, _ := strconv.Atoi(values["attempts"])
+	runAt, _ := strconv.ParseInt(values["run_at"], 10, 64)
+	return &Job{
+		ID:        uint(jobID),
+		Queue:     values["queue"],
+		Priority:  priority,
+		Type:      values["type"],
+		Data:      values["data"],
+		Attempts:  attempts,
+		ErrorMsg:  values["error"],
+		RequestID: values["request_id"],
+		Workflow:  values["workflow"],
+		RunAt:     time.UnixMilli(runAt),
+	}, nil
+}
+
+// owned 脚本返回 0 表示任务已不归当前 worker 所有
+func owned(cmd *redis.Cmd) error {
+	n, err := cmd.Int()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// ack 确认任务执行成功并删除
+func (q *Redis) ack(job *Job) error {
+	return owned(redisAckScript.Run(context.Background(), q.rdb,
+		[]string{q.key(job.Queue, "reserved"), q.jobKey(job.ID)},
+		job.ID, q.id, time.Now().UnixMilli(),
+	))
+}
+
+// retry 放回队列，delay 后再次执行
+func (q *Redis) retry(job *Job, delay time.Duration, execErr error) error {
+	return owned(redisRetryScript.Run(context.Background(), q.rdb,
+		[]string{q.key(job.Queue, "reserved"), q.jobKey(job.ID), q.key(job.Queue, "delayed")},
+		job.ID, q.id, time.Now().Add(delay).UnixMilli(), execErr.Error(),
+	))
+}
+
+// bury 移入死信
+func (q *Redis) bury(job *Job, execErr error) error {
+	return owned(redisBuryScript.Run(context.Background(), q.rdb,
+		[]string{q.key(job.Queue, "reserved"), q.jobKey(job.ID), q.key("failed")},
+		job.ID, execErr.Error(), time.Now().UnixMilli(), q.id, q.failedLimit, q.prefix,
+	))
+}
+
+// touch 续约执行中的任务
+func (q *Redis) touch(job *Job) error {
+	return redisTouchScript.Run(context.Background(), q.rdb,
+		[]string{q.jobKey(job.ID)},
+		q.id, time.Now().UnixMilli(),
+	).Err()
+}
+
+// reap 将租约过期的任务放回队列
+func (q *Redis) reap(lease time.Duration) (int64, error) {
+	names, err := q.queueNames()
+	if err != nil {
+		return 0, err
+	}
+	ctx := context.Background()
+	now := time.Now()
+	var total int64
+	for _, name := range names {
+		n, err := redisReapScript.Run(ctx, q.rdb,
+			[]string{q.key(name, "reserved"), q.key(name, "ready")},
+			now.Add(-lease).UnixMilli(), now.UnixMilli(), q.prefix,
+		).Int64()
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// release 将被中断的任务立即放回队列
+func (q *Redis) release(job *Job) error {
+	return redisReleaseScript.Run(context.Background(), q.rdb,
+		[]string{q.key(job.Queue, "reserved"), q.jobKey(job.ID), q.key(job.Queue, "ready")},
+		job.ID, q.id, time.Now().UnixMilli(),
+	).Err()
+}
+
+// depth 按队列和状态统计未完成的任务数
+func (q *Redis) depth() (map[string]map[TaskStatus]int64, error) {
+	names, err := q.queueNames()
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	depth := make(map[string]map[TaskStatus]int64)
+	for _, name := range names {
+		pipe := q.rdb.Pipeline()
+		delayed := pipe.ZCard(ctx, q.key(name, "delayed"))
+		ready := pipe.ZCard(ctx, q.key(name, "ready"))
+		reserved := pipe.LLen(ctx, q.key(name, "reserved"))
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+		depth[name] = map[TaskStatus]int64{
+			TaskStatusPending:  delayed.Val() + ready.Val(),
+			TaskStatusReserved: reserved.Val(),
+		}
+	}
+	return depth, nil
+}
+
+// createBatch 保存新批次
+func (q *Redis) createBatch(b *BatchInfo) error {
+	return q.rdb.HSet(context.Background(), q.key("batch", b.ID),
+		"name", b.Name,
+		"total", b.Total,
+		"pending", b.Pending,
+		"succeeded", b.Succeeded,
+		"failed", b.Failed,
+		"then", b.Then,
+		"catch", b.Catch,
+		"finally", b.Finally,
+		"created_at", b.CreatedAt.UnixMilli(),
+	).Err()
+}
+
+// findBatch 查询批次
+func (q *Redis) findBatch(id string) (*BatchInfo, error) {
+	values, err := q.rdb.HGetAll(context.Background(), q.key("batch", id)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, ErrBatchNotFound
+	}
+	return toBatch(id, values), nil
+}
+
+// finishBatchJob 记录批次内一个任务结束
+func (q *Redis) finishBatchJob(id string, succeeded bool) (*BatchInfo, error) {
+	counter := "failed"
+	if succeeded {
+		counter = "succeeded"
+	}
+	list, err := redisBatchScript.Run(context.Background(), q.rdb,
+		[]string{q.key("batch", id)},
+		counter, time.Now().UnixMilli(),
+	).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrBatchNotFound
+	}
+	values := make(map[string]string, len(list)/2)
+	for i := 0; i+1 < len(list); i += 2 {
+		values[list[i]] = list[i+1]
+	}
+	return toBatch(id, values), nil
+}
+
+// cancelBatch 取消批次
+func (q *Redis) cancelBatch(id string) error {
+	if _, err := q.findBatch(id); err != nil {
+		return err
+	}
+	return q.rdb.HSetNX(context.Background(), q.key("batch", id), "cancelled_at", time.Now().UnixMilli()).Err()
+}
+
+// abortBatch 推送中断时扣除未推送的任务并取消批次
+func (q *Redis) abortBatch(id string, missing int) error {
+	n, err := redisAbortBatchScript.Run(context.Background(), q.rdb,
+		[]string{q.key("batch", id)},
+		missing, time.Now().UnixMilli(),
+	).Int()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrBatchNotFound
+	}
+	return nil
+}
+
+// toBatch 将 Redis HASH 转换为 BatchInfo
+func toBatch(id string, values map[string]string) *BatchInfo {
+	atoi := func(key string) int {
+		n, _ := strconv.Atoi(values[key])
+		return n
+	}
+	msTime := func(key string) *time.Time {
+		ms, err := strconv.ParseInt(values[key], 10, 64)
+		if err != nil {
+			return nil
+		}
+		t := time.UnixMilli(ms)
+		return &t
+	}
+	batch := &BatchInfo{
+		ID:          id,
+		Name:        values["name"],
+		Total:       atoi("total"),
+		Pending:     atoi("pending"),
+		Succeeded:   atoi("succeeded"),
+		Failed:      atoi("failed"),
+		Then:        values["then"],
+		Catch:       values["catch"],
+		Finally:     values["finally"],
+		CancelledAt: msTime("cancelled_at"),
+		FinishedAt:  msTime("finished_at"),
+	}
+	if createdAt := msTime("created_at"); createdAt != nil {
+		batch.CreatedAt = *createdAt
+	}
+	return batch
+}
@@ -0,0 +1,153 @@
+package queue
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedis(t *testing.T, cfg *Config) (*Redis, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	iq, err := NewRedisQueue(rdb, cfg)
+	require.NoError(t, err)
+	q := iq.(*Redis)
+	require.NoError(t, q.Register(&mockTask{}))
+	return q, mr
+}
+
+func TestRedis_ReserveAck(t *testing.T) {
+	q, mr := newTestRedis(t, &Config{})
+	mustPush(t, q, &mockTask{Name: "a"}, 0)
+	mustPush(t, q, &mockTask{Name: "later"}, time.Hour)
+
+	jobs, err := q.reserveBatch(10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, []string{"a"}, jobNames(t, q.worker, jobs))
+	assert.Equal(t, 1, jobs[0].Attempts)
+
+	// 已领取的任务不会被再次取出
+	again, err := q.reserveBatch(10)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	require.NoError(t, q.ack(jobs[0]))
+	assert.False(t, mr.Exists(q.jobKey(jobs[0].ID)))
+	list, _ := mr.List(q.key(DefaultQueue, "reserved"))
+	assert.Empty(t, list)
+}
+
+func TestRedis_RetryAndBury(t *testing.T) {
+	q, mr := newTestRedis(t, &Config{})
+	mustPush(t, q, &mockTask{Name: "a"}, 0)
+	policy := RetryPolicy{MaxAttempts: 2}
+
+	jobs, err := q.reserveBatch(1)
+	require.NoError(t, err)
+	require.NoError(t, q.fail(jobs[0], policy, errors.New("boom")))
+
+	jobs, err = q.reserveBatch(1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, 2, jobs[0].Attempts)
+	assert.Equal(t, "boom", jobs[0].ErrorMsg)
+
+	// 重试耗尽后进入死信
+	require.NoError(t, q.fail(jobs[0], policy, errors.New("boom again")))
+	failed, err := mr.List(q.key("failed"))
+	require.NoError(t, err)
+	assert.Len(t, failed, 1)
+	assert.Equal(t, "boom again", mr.HGet(q.jobKey(jobs[0].ID), "error"))
+}
+
+func TestRedis_FailedLimit(t *testing.T) {
+	q, mr := newTestRedis(t, &Config{})
+	q.failedLimit = 2
+	var ids []uint
+	for _, name := range []string{"a", "b", "c"} {
+		mustPush(t, q, &mockTask{Name: name}, 0)
+		jobs, err := q.reserveBatch(1)
+		require.NoError(t, err)
+		require.NoError(t, q.fail(jobs[0], RetryPolicy{MaxAttempts: 1}, errors.New("boom")))
+		ids = append(ids, jobs[0].ID)
+	}
+	// 只保留最近的死信，删除最旧死信的任务数据
+	failed, err := mr.List(q.key("failed"))
+	require.NoError(t, err)
+	assert.Len(t, failed, 2)
+	assert.False(t, mr.Exists(q.jobKey(ids[0])))
+	assert.True(t, mr.Exists(q.jobKey(ids[1])))
+	assert.True(t, mr.Exists(q.jobKey(ids[2])))
+}
+
+func TestRedis_Reap(t *testing.T) {
+	q, _ := newTestRedis(t, &Config{})
+	mustPush(t, q, &mockTask{Name: "a"}, 0)
+
+	jobs, err := q.reserveBatch(1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+
+	n, err := q.reap(time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	time.Sleep(10 * time.Millisecond)
+	n, err = q.reap(time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	jobs, err = q.reserveBatch(1)
+	require.NoError(t, err)
+	assert.Len(t, jobs, 1)
+}
+
+func TestRedis_QueuesAndPriority(t *testing.T) {
+	q, _ := newTestRedis(t, &Config{Queues: []string{"mail", "default"}, Strict: true})
+
+	mustPush(t, q, &mockTask{Name: "bulk"}, 0, WithQueue("bulk"))
+	mustPush(t, q, &mockTask{Name: "low"}, 0)
+	mustPush(t, q, &mockTask{Name: "high"}, 0, WithPriority(10))
+	mustPush(t, q, &mockTask{Name: "reset"}, 0, WithQueue("mail"))
+
+	jobs, err := q.reserveBatch(10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"reset", "high", "low"}, jobNames(t, q.worker, jobs))
+}
+
+func TestRedis_Unique(t *testing.T) {
+	q, mr := newTestRedis(t, &Config{})
+	testUnique(t, q, q.worker, mr.FastForward)
+}
+
+func TestRedis_LeaseLost(t *testing.T) {
+	q, mr := newTestRedis(t, &Config{})
+	mustPush(t, q, &mockTask{Name: "a"}, 0)
+	jobs, err := q.reserveBatch(1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+
+	// 租约过期后被其他 worker 领取
+	mr.HSet(q.jobKey(jobs[0].ID), "reserved_by", "other-worker")
+	assert.ErrorIs(t, q.bury(jobs[0], errors.New("boom")), ErrLeaseLost)
+	assert.ErrorIs(t, q.ack(jobs[0]), ErrLeaseLost)
+	assert.ErrorIs(t, q.retry(jobs[0], 0, errors.New("boom")), ErrLeaseLost)
+	assert.True(t, mr.Exists(q.jobKey(jobs[0].ID)))
+	assert.False(t, mr.Exists(q.key("failed")))
+	assert.Equal(t, "other-worker", mr.HGet(q.jobKey(jobs[0].ID), "reserved_by"))
+}
+
+func TestRedis_HashTagPrefix(t *testing.T) {
+	q, _ := newTestRedis(t, &Config{})
+	assert.Equal(t, "{queue}:job:1", q.jobKey(1))
+	q, _ = newTestRedis(t, &Config{Prefix: "app:queue:"})
+	assert.Equal(t, "{app:queue}:default:ready", q.key(DefaultQueue, "ready"))
+	q, _ = newTestRedis(t, &Config{Prefix: "app:{q}:"})
+	assert.Equal(t, "app:{q}:id", q.key("id"))
+}
@@ -0,0 +1,73 @@
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+// RetryPolicy 任务重试策略
+type RetryPolicy struct {
+	MaxAttempts int           // 最大执行次数（含首次），<= 1 表示不重试
+	Backoff     time.Duration // 首次重试的等待时间，之后按 2 的指数递增
+	MaxBackoff  time.Duration // 重试等待时间上限，0 表示不限制
+}
+
+// DefaultRetryPolicy 任务未实现 IRetryTask 时使用的默认策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	Backoff:     5 * time.Second,
+	MaxBackoff:  10 * time.Minute,
+}
+
+// IRetryTask 可选接口，任务实现后可自定义重试策略
+type IRetryTask interface {
+	RetryPolicy() RetryPolicy
+}
+
+// GetRetryPolicy 获取任务的重试策略
+func GetRetryPolicy(task ITask) RetryPolicy {
+	if t, ok := task.(IRetryTask); ok {
+		return t.RetryPolicy()
+	}
+	return DefaultRetryPolicy
+}
+
+// ShouldRetry 判断第 attempts 次执行失败后是否还需要重试
+func (p RetryPolicy) ShouldRetry(attempts int) bool {
+	return attempts < p.MaxAttempts
+}
+
+// NextDelay 计算第 attempts 次执行失败后的重试等待时间
+//
+//	Backoff * 2^(attempts-1)，不超过 MaxBackoff
+func (p RetryPolicy) NextDelay(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := p.Backoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return delay
+}
+
+type attemptsKey struct{}
+
+// WithAttempts 将当前执行次数写入 context
+func WithAttempts(ctx context.Context, attempts int) context.Context {
+	return context.WithValue(ctx, attemptsKey{}, attempts)
+}
+
+// GetAttempts 获取任务当前是第几次执行（从 1 开始）
+func GetAttempts(ctx context.Context) int {
+	if n, ok := ctx.Value(attemptsKey{}).(int); ok {
+		return n
+	}
+	return 1
+}
@@ -0,0 +1,28 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_NextDelay(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	assert.Equal(t, time.Second, p.NextDelay(1))
+	assert.Equal(t, 2*time.Second, p.NextDelay(2))
+	assert.Equal(t, 4*time.Second, p.NextDelay(3))
+	// 超过上限后固定为 MaxBackoff
+	assert.Equal(t, 5*time.Second, p.NextDelay(4))
+	assert.Equal(t, 5*time.Second, p.NextDelay(10))
+}
+
+func TestRetryPolicy_ShouldRetry(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3}
+
+	assert.True(t, p.ShouldRetry(1))
+	assert.True(t, p.ShouldRetry(2))
+	assert.False(t, p.ShouldRetry(3))
+	assert.False(t, RetryPolicy{}.ShouldRetry(1))
+}
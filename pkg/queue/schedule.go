@@ -0,0 +1,163 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log/slog"
+	"time"
+)
+
+const (
+	DefaultScheduleLockName = "scheduler"      // 默认调度锁名称
+	DefaultScheduleLockTTL  = 30 * time.Second // 调度锁有效期，leader 宕机后其他实例最多等待该时长接管
+)
+
+var (
+	schedules []*ScheduleEntry
+	// cron 表达式解析器，支持标准 5 段格式和 @daily、@every 1h 等描述符
+	cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+)
+
+// ScheduleEntry 周期任务
+type ScheduleEntry struct {
+	Spec     string        // cron 表达式
+	Task     ITask         // 到期时推送的任务
+	Opts     []PushOption  // 推送选项
+	schedule cron.Schedule // 解析后的调度规则
+}
+
+// Schedule 注册周期任务，spec 为 cron 表达式，例如 "0 3 * * *" 表示每天 3 点
+//
+//	到期时由 schedule:run 推送到队列，任务本身仍需通过 Register 注册给 worker 执行
+func Schedule(spec string, task ITask, opts ...PushOption) {
+	schedule, err := cronParser.Parse(spec)
+	if err != nil {
+		panic(fmt.Sprintf("invalid schedule spec %q: %v", spec, err))
+	}
+	schedules = append(schedules, &ScheduleEntry{Spec: spec, Task: task, Opts: opts, schedule: schedule})
+}
+
+// GetSchedules 获取已注册的周期任务
+func GetSchedules() []*ScheduleEntry {
+	return schedules
+}
+
+// SysScheduleLock 调度器 leader 锁，多个实例中只有持有锁的实例推送周期任务
+type SysScheduleLock struct {
+	Name      string    `gorm:"primaryKey;size:100"`
+	Owner     string    `gorm:"size:100"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+// Scheduler 周期任务调度器
+type Scheduler struct {
+	db      *gorm.DB
+	queue   IQueue
+	entries []*ScheduleEntry
+	name    string        // 锁名称
+	owner   string        // 当前实例标识
+	ttl     time.Duration // 锁有效期
+	leader  bool          // 当前是否持有锁
+	next    map[*ScheduleEntry]time.Time
+}
+
+// NewScheduler 创建调度器
+func NewScheduler(db *gorm.DB, queue IQueue, entries []*ScheduleEntry) *Scheduler {
+	_ = db.AutoMigrate(&SysScheduleLock{})
+	return &Scheduler{
+		db:      db,
+		queue:   queue,
+		entries: entries,
+		name:    DefaultScheduleLockName,
+		owner:   newWorkerID(),
+		ttl:     DefaultScheduleLockTTL,
+		next:    make(map[*ScheduleEntry]time.Time),
+	}
+}
+
+// acquire 获取或续约 leader 锁
+func (s *Scheduler) acquire() (bool, error) {
+	now := time.Now()
+	// 续约自己的锁，或抢占已过期的锁
+	result := s.db.Model(&SysScheduleLock{}).
+		Where("name = ? AND (owner = ? OR expires_at < ?)", s.name, s.owner, now).
+		Updates(map[string]any{"owner": s.owner, "expires_at": now.Add(s.ttl)})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+	// 锁不存在时创建
+	result = s.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&SysScheduleLock{Name: s.name, Owner: s.owner, ExpiresAt: now.Add(s.ttl)})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// release 释放 leader 锁，便于其他实例立即接管
+func (s *Scheduler) release() error {
+	return s.db.Where("name = ? AND owner = ?", s.name, s.owner).Delete(&SysScheduleLock{}).Error
+}
+
+// Tick 检查一次周期任务，leader 推送已到期的任务，返回推送的数量
+func (s *Scheduler) Tick(now time.Time) (int, error) {
+	leader, err := s.acquire()
+	if err != nil {
+		return 0, err
+	}
+	if leader != s.leader {
+		slog.Info("[SCHEDULER] leadership changed", slog.Bool("leader", leader), slog.String("owner", s.owner))
+		s.leader = leader
+		// 重新成为 leader 时从当前时间开始计算，不补推错过的周期
+		clear(s.next)
+	}
+	if !leader {
+		return 0, nil
+	}
+	pushed := 0
+	for _, entry := range s.entries {
+		next, ok := s.next[entry]
+		if !ok {
+			s.next[entry] = entry.schedule.Next(now)
+			continue
+		}
+		if now.Before(next) {
+			continue
+		}
+		s.next[entry] = entry.schedule.Next(now)
+		if _, err := s.queue.Push(entry.Task, 0, entry.Opts...); err != nil {
+			slog.Warn("[SCHEDULER] push task error", slog.Any("err", err), slog.String("spec", entry.Spec))
+			continue
+		}
+		pushed++
+	}
+	return pushed, nil
+}
+
+// Run 每秒检查一次周期任务，直到 ctx 结束
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if s.leader {
+				if err := s.release(); err != nil {
+					slog.Warn("[SCHEDULER] release lock error", slog.Any("err", err))
+				}
+			}
+			slog.Warn("[SCHEDULER] Stopped")
+			return
+		case now := <-ticker.C:
+			if _, err := s.Tick(now); err != nil {
+				slog.Warn("[SCHEDULER] tick error", slog.Any("err", err))
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordQueue 记录推送的任务
+type recordQueue struct {
+	pushed []ITask
+}
+
+func (r *recordQueue) Register(task ITask) error { return nil }
+func (r *recordQueue) Push(task ITask, delay time.Duration, opts ...PushOption) (uint, error) {
+	r.pushed = append(r.pushed, task)
+	return uint(len(r.pushed)), nil
+}
+func (r *recordQueue) Use(mws ...Middleware)                             {}
+func (r *recordQueue) Start(ctx context.Context, interval time.Duration) {}
+func (r *recordQueue) Stop()                                             {}
+
+func TestScheduler_Leader(t *testing.T) {
+	db := newTestDB(t)
+	schedule, err := cronParser.Parse("* * * * *")
+	require.NoError(t, err)
+	entries := []*ScheduleEntry{{Spec: "* * * * *", Task: &mockTask{Name: "cron"}, schedule: schedule}}
+
+	q1, q2 := &recordQueue{}, &recordQueue{}
+	s1 := NewScheduler(db, q1, entries)
+	s2 := NewScheduler(db, q2, entries)
+
+	now := time.Date(2025, 1, 1, 0, 0, 30, 0, time.Local)
+	for i := 0; i < 3; i++ {
+		// 每次前进一分钟，两个实例同时检查
+		tick := now.Add(time.Duration(i) * time.Minute)
+		_, err := s1.Tick(tick)
+		require.NoError(t, err)
+		_, err = s2.Tick(tick)
+		require.NoError(t, err)
+	}
+	// 只有 leader 推送任务，首个周期只计算下次执行时间
+	assert.True(t, s1.leader)
+	assert.False(t, s2.leader)
+	assert.Len(t, q1.pushed, 2)
+	assert.Empty(t, q2.pushed)
+
+	// leader 释放锁后其他实例接管
+	require.NoError(t, s1.release())
+	_, err = s2.Tick(now.Add(3 * time.Minute))
+	require.NoError(t, err)
+	assert.True(t, s2.leader)
+}
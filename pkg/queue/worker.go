@@ -0,0 +1,448 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Job 已领取、待执行的任务
+type Job struct {
+	ID        uint      // 任务 ID
+	Queue     string    // 队列名
+	Priority  int       // 优先级
+	Type      string    // 任务类型
+	Data      string    // 任务数据（JSON）
+	Attempts  int       // 含本次在内的执行次数
+	ErrorMsg  string    // 上一次执行的错误
+	RequestID string    // 推送任务时的请求 ID
+	Workflow  string    // 链式、批量任务的流程信息（JSON）
+	RunAt     time.Time // 到期时间，用于统计等待时长
+}
+
+// ErrLeaseLost 任务租约已失效：已被回收并可能由其他 worker 执行，本次执行结果不再写回
+var ErrLeaseLost = errors.New("queue: job lease lost")
+
+// driver 队列存储驱动，负责任务的领取、确认、重试和回收
+type driver interface {
+	// reserve 从指定队列领取最多 n 条到期任务，queue 为空表示所有队列
+	reserve(queue string, n int) ([]*Job, error)
+	// ack 确认任务执行成功并删除
+	ack(job *Job) error
+	// retry 将任务放回队列，delay 后再次执行
+	retry(job *Job, delay time.Duration, execErr error) error
+	// bury 将任务移入死信
+	bury(job *Job, execErr error) error
+	// touch 续约执行中的任务
+	touch(job *Job) error
+	// reap 将租约过期的任务放回队列，返回放回的数量
+	reap(lease time.Duration) (int64, error)
+	// release 将被中断的任务立即放回队列，本次执行不计入执行次数
+	release(job *Job) error
+}
+
+// flight 执行中的任务
+type flight struct {
+	cancel   context.CancelFunc // 取消任务的 context
+	released bool               // 是否已因停止超时被放回队列
+}
+
+// worker 各驱动共用的任务注册表和消费循环
+type worker struct {
+	name     string // 日志前缀
+	driver   driver
+	queue    IQueue // 传给 ITask.Execute 的队列实例
+	registry map[string]reflect.Type
+	mu       sync.RWMutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	id       string        // 当前 worker 标识
+	lease    time.Duration // 任务租约时长
+	workers  int           // 并发 worker 数
+	batch    int           // 每次拉取的最大任务数
+	maxPoll  time.Duration // 空闲时最长拉取间隔
+	queues   []queueWeight // 要消费的队列，为空时消费所有队列
+	strict   bool          // 严格按队列顺序消费
+	mws      []Middleware  // 任务中间件
+	drain    time.Duration // 停止时等待执行中任务的最长时间
+	done     chan struct{} // Start 退出时关闭
+	inflight map[*Job]*flight
+	flightMu sync.Mutex
+}
+
+// newWorker 根据配置创建 worker
+func newWorker(name string, cfg *Config) (*worker, error) {
+	queues, err := parseQueues(cfg.Queues)
+	if err != nil {
+		return nil, err
+	}
+	lease := cfg.LeaseTimeout
+	if lease <= 0 {
+		lease = DefaultLeaseTimeout
+	}
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	batch := cfg.BatchSize
+	if batch <= 0 {
+		batch = DefaultBatchSize
+	}
+	maxPoll := cfg.MaxPollInterval
+	if maxPoll <= 0 {
+		maxPoll = DefaultMaxPollInterval
+	}
+	drain := cfg.DrainTimeout
+	if drain <= 0 {
+		drain = DefaultDrainTimeout
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &worker{
+		name:     name,
+		registry: make(map[string]reflect.Type),
+		ctx:      ctx,
+		cancel:   cancel,
+		id:       newWorkerID(),
+		lease:    time.Duration(lease) * time.Second,
+		workers:  workers,
+		batch:    batch,
+		maxPoll:  time.Duration(maxPoll) * time.Millisecond,
+		queues:   queues,
+		strict:   cfg.Strict,
+		drain:    time.Duration(drain) * time.Second,
+		inflight: make(map[*Job]*flight),
+	}, nil
+}
+
+// newWorkerID 生成 worker 标识：主机名-进程号-随机串
+func newWorkerID() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8])
+}
+
+// Register 注册任务类型
+func (w *worker) Register(task ITask) error {
+	typeName, err := GetTaskTypeName(task)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.registry[typeName] = reflect.TypeOf(task).Elem()
+	return nil
+}
+
+// Use 注册任务中间件，需在 Start 前调用
+func (w *worker) Use(mws ...Middleware) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.mws = append(w.mws, mws...)
+}
+
+// handler 返回包裹了中间件的任务执行函数
+func (w *worker) handler() Handler {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return chain(func(ctx context.Context, job *Job, task ITask) error {
+		return task.Execute(ctx, w.queue)
+	}, w.mws)
+}
+
+// encode 序列化任务，返回任务类型和数据
+func encode(task ITask) (string, string, error) {
+	typeName, err := GetTaskTypeName(task)
+	if err != nil {
+		return "", "", err
+	}
+	data, err := json.Marshal(task)
+	if err != nil {
+		return "", "", err
+	}
+	return typeName, string(data), nil
+}
+
+// decode 根据注册表反序列化任务
+func (w *worker) decode(job *Job) (ITask, error) {
+	// 获取任务类型
+	w.mu.RLock()
+	typ, ok := w.registry[job.Type]
+	w.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unregistered task type: %s", job.Type)
+	}
+
+	// 反序列化任务数据
+	task := reflect.New(typ).Interface().(ITask)
+	if err := json.Unmarshal([]byte(job.Data), task); err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// reserveBatch 领取最多 n 条到期任务
+//
+//	配置了 Queues 时按队列顺序（严格或加权）依次领取，同一队列内按优先级和执行时间排序
+func (w *worker) reserveBatch(n int) ([]*Job, error) {
+	if len(w.queues) == 0 {
+		return w.driver.reserve("", n)
+	}
+	var reserved []*Job
+	for _, name := range orderQueues(w.queues, w.strict) {
+		jobs, err := w.driver.reserve(name, n-len(reserved))
+		if err != nil {
+			return reserved, err
+		}
+		reserved = append(reserved, jobs...)
+		if len(reserved) >= n {
+			break
+		}
+	}
+	return reserved, nil
+}
+
+// fail 按重试策略放回队列，重试耗尽则移入死信
+func (w *worker) fail(job *Job, policy RetryPolicy, execErr error) error {
+	if !policy.ShouldRetry(job.Attempts) {
+		return w.driver.bury(job, execErr)
+	}
+	return w.driver.retry(job, policy.NextDelay(job.Attempts), execErr)
+}
+
+// heartbeat 任务执行期间定期续约，避免长任务被 reaper 重新投递
+func (w *worker) heartbeat(ctx context.Context, job *Job) {
+	ticker := time.NewTicker(w.lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.driver.touch(job); err != nil {
+				slog.Warn(w.name+" heartbeat error", slog.Any("err", err))
+			}
+		}
+	}
+}
+
+// reaper 定期回收租约过期的任务
+func (w *worker) reaper() {
+	ticker := time.NewTicker(w.lease / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := w.driver.reap(w.lease)
+			if err != nil {
+				slog.Warn(w.name+" reap error", slog.Any("err", err))
+				continue
+			}
+			if n > 0 {
+				slog.Warn(w.name+" released expired tasks", slog.Int64("count", n))
+			}
+		}
+	}
+}
+
+// execute 执行任务，成功则确认删除，失败则按重试策略处理
+func (w *worker) execute(ctx context.Context, job *Job) {
+	observeStart(job)
+	wf := parseWorkflow(job)
+	task, err := w.decode(job)
+	if err != nil {
+		// 无法解析的任务直接进入死信
+		slog.Warn(w.name+" decode task error", slog.Any("err", err), slog.String("type", job.Type))
+		if err := w.driver.bury(job, err); err != nil {
+			slog.Warn(w.name+" bury task error", slog.Any("err", err))
+			return
+		}
+		metricFailed.WithLabelValues(job.Type, job.Queue).Inc()
+		w.advance(job, wf, nil)
+		return
+	}
+
+	hbCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	go w.heartbeat(hbCtx, job)
+
+	start := time.Now()
+	err = w.handler()(w.withWorkflowContext(WithAttempts(ctx, job.Attempts), wf), job, task)
+	metricDuration.WithLabelValues(job.Type, job.Queue).Observe(time.Since(start).Seconds())
+	if w.released(job) {
+		// 停止超时已放回队列，忽略本次执行结果
+		return
+	}
+	if err != nil {
+		slog.Warn(w.name+" Execute task error", slog.Any("err", err), slog.String("type", job.Type), slog.Int("attempts", job.Attempts))
+		policy := GetRetryPolicy(task)
+		if err := w.fail(job, policy, err); err != nil {
+			slog.Warn(w.name+" retry task error", slog.Any("err", err))
+			return
+		}
+		if policy.ShouldRetry(job.Attempts) {
+			metricRetried.WithLabelValues(job.Type, job.Queue).Inc()
+			return
+		}
+		metricFailed.WithLabelValues(job.Type, job.Queue).Inc()
+		w.advance(job, wf, nil)
+		return
+	}
+	if err := w.driver.ack(job); err != nil {
+		slog.Warn(w.name+" ack task error", slog.Any("err", err))
+		return
+	}
+	metricProcessed.WithLabelValues(job.Type, job.Queue).Inc()
+	w.advance(job, wf, task)
+}
+
+// run 在可取消的 context 中执行任务，执行期间登记为执行中
+func (w *worker) run(ctx context.Context, job *Job) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w.flightMu.Lock()
+	w.inflight[job] = &flight{cancel: cancel}
+	w.flightMu.Unlock()
+	defer func() {
+		w.flightMu.Lock()
+		delete(w.inflight, job)
+		w.flightMu.Unlock()
+	}()
+	w.execute(ctx, job)
+}
+
+// released 任务是否已因停止超时被放回队列
+func (w *worker) released(job *Job) bool {
+	w.flightMu.Lock()
+	defer w.flightMu.Unlock()
+	f, ok := w.inflight[job]
+	return ok && f.released
+}
+
+// releaseInflight 取消所有执行中的任务并放回队列，返回放回的数量
+func (w *worker) releaseInflight() int {
+	w.flightMu.Lock()
+	defer w.flightMu.Unlock()
+	count := 0
+	for job, f := range w.inflight {
+		if f.released {
+			continue
+		}
+		f.released = true
+		f.cancel()
+		if err := w.driver.release(job); err != nil {
+			slog.Warn(w.name+" release task error", slog.Any("err", err), slog.Uint64("id", uint64(job.ID)))
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// Start 启动队列
+//
+//	最多同时执行 workers 个任务，只在有空闲 worker 时才拉取；
+//	拉取到任务后立即再次拉取，队列空闲时拉取间隔从 interval 开始翻倍退避到 maxPoll
+func (w *worker) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultPollInterval * time.Millisecond
+	}
+	// 任务只继承 ctx 中的值，不随 ctx 取消：调用方可能传入启动阶段带超时的 context，
+	// 任务和心跳由 Stop 在等待超时后取消
+	ctx = context.WithoutCancel(ctx)
+	done := make(chan struct{})
+	w.mu.Lock()
+	w.done = done
+	w.mu.Unlock()
+	defer close(done)
+	go w.reaper()
+	sem := make(chan struct{}, w.workers) // 占用中的 worker
+	idle := make(chan struct{}, 1)        // worker 空闲通知
+	wait := time.Duration(0)
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		select {
+		case <-w.ctx.Done():
+			slog.Warn(w.name + " Stopped")
+			return
+		case <-timer.C:
+		}
+		free := w.workers - len(sem)
+		if free <= 0 {
+			// 所有 worker 都在忙，等待空闲后再拉取
+			select {
+			case <-w.ctx.Done():
+				slog.Warn(w.name + " Stopped")
+				return
+			case <-idle:
+			}
+			timer.Reset(0)
+			continue
+		}
+		jobs, err := w.reserveBatch(min(w.batch, free))
+		if err != nil {
+			slog.Warn(w.name+" pop error", slog.Any("err", err))
+		}
+		for _, job := range jobs {
+			sem <- struct{}{}
+			w.wg.Add(1)
+			go func(job *Job) {
+				defer func() {
+					w.wg.Done()
+					<-sem
+					select {
+					case idle <- struct{}{}:
+					default:
+					}
+				}()
+				w.run(ctx, job)
+			}(job)
+		}
+		// 计算下一次拉取间隔
+		switch {
+		case len(jobs) > 0:
+			wait = 0
+		case wait < interval:
+			wait = interval
+		default:
+			wait = min(wait*2, max(w.maxPoll, interval))
+		}
+		timer.Reset(wait)
+	}
+}
+
+// Stop 停止队列
+//
+//	先停止拉取新任务，再等待执行中的任务完成；超过 drain 仍未完成的任务会取消 context 并放回队列
+func (w *worker) Stop() {
+	w.cancel()
+	w.mu.RLock()
+	done := w.done
+	w.mu.RUnlock()
+	if done != nil {
+		<-done
+	}
+	finished := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(finished)
+	}()
+	timer := time.NewTimer(w.drain)
+	defer timer.Stop()
+	select {
+	case <-finished:
+	case <-timer.C:
+		n := w.releaseInflight()
+		slog.Warn(w.name+" drain timeout, released unfinished tasks", slog.Int("count", n))
+	}
+}
@@ -0,0 +1,103 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockTask 阻塞到 context 取消
+type blockTask struct{}
+
+var blockStarted = make(chan struct{}, 1)
+
+func (b *blockTask) Execute(ctx context.Context, q IQueue) error {
+	blockStarted <- struct{}{}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// startTestQueue 在后台启动队列
+func startTestQueue(q IQueue) {
+	go q.Start(context.Background(), 10*time.Millisecond)
+}
+
+func TestWorker_StopWaitsForJobs(t *testing.T) {
+	q := newTestGorm(t, &Config{})
+	mustPush(t, q, &mockTask{Name: "a"}, 0)
+	startTestQueue(q)
+	require.Eventually(t, func() bool {
+		var count int64
+		q.db.Model(&SysTask{}).Count(&count)
+		return count == 0
+	}, time.Second, 10*time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		q.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not return")
+	}
+}
+
+func TestWorker_StopReleasesUnfinishedJobs(t *testing.T) {
+	q := newTestGorm(t, &Config{DrainTimeout: 1})
+	require.NoError(t, q.Register(&blockTask{}))
+	mustPush(t, q, &blockTask{}, 0)
+	startTestQueue(q)
+	<-blockStarted
+
+	start := time.Now()
+	q.Stop()
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+
+	// 未完成的任务放回队列，本次执行不计入次数
+	var task SysTask
+	require.NoError(t, q.db.First(&task).Error)
+	assert.Equal(t, TaskStatusPending, task.Status)
+	assert.Equal(t, 0, task.Attempts)
+	assert.Empty(t, task.ReservedBy)
+	assert.Empty(t, task.ErrorMsg)
+}
+
+type expiredCtxKey struct{}
+
+// expiredCtxTask 记录执行时 context 的状态
+type expiredCtxTask struct{}
+
+var expiredCtxResult = make(chan error, 1)
+
+func (c *expiredCtxTask) Execute(ctx context.Context, q IQueue) error {
+	if ctx.Value(expiredCtxKey{}) != "app" {
+		expiredCtxResult <- context.Canceled
+		return nil
+	}
+	expiredCtxResult <- ctx.Err()
+	return nil
+}
+
+func TestWorker_StartWithExpiredContext(t *testing.T) {
+	q := newTestGorm(t, &Config{})
+	require.NoError(t, q.Register(&expiredCtxTask{}))
+	// 模拟 fx 启动阶段的 context：启动完成后已超时
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), expiredCtxKey{}, "app"), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+
+	go q.Start(ctx, 10*time.Millisecond)
+	defer q.Stop()
+	mustPush(t, q, &expiredCtxTask{}, 0)
+	select {
+	case err := <-expiredCtxResult:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("task did not run")
+	}
+}
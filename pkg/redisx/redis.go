@@ -0,0 +1,23 @@
+package redisx
+
+import (
+	"github.com/redis/go-redis/v9"
+)
+
+// Config Redis 配置结构体
+type Config struct {
+	Addr     string `yaml:"addr" json:"addr,omitempty"`         // 地址，host:port
+	Password string `yaml:"password" json:"password,omitempty"` // 密码
+	DB       int    `yaml:"db" json:"db,omitempty"`             // 数据库编号
+}
+
+// New 根据配置创建 Redis 客户端
+//
+//	客户端在首次使用时才会建立连接
+func New(cfg *Config) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+}
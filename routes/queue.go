@@ -7,4 +7,8 @@ import (
 
 func init() {
 	queue.Register(&tasks.EmailTask{})
+	queue.Register(&tasks.PruneAccessLogTask{})
+
+	// 周期任务
+	queue.Schedule("0 3 * * *", &tasks.PruneAccessLogTask{Days: 30}) // 每天 3 点清理 30 天前的访问日志
 }
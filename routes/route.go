@@ -9,4 +9,6 @@ import (
 func init() {
 	httpx.RegisterRoute(&apis.Index{})
 	httpx.RegisterRoute(&apis.Task{})
+	httpx.RegisterRoute(&apis.Metrics{})
+	httpx.RegisterRoute(&apis.JWKS{})
 }